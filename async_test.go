@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+ * Test sending messages asynchronously, with the outcome being reported to a
+ * CompletionListener.
+ */
+func TestAsyncSend(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	listener := &recordingListener{}
+	producer := context.CreateProducer().SetAsync(listener)
+	assert.Equal(t, listener, producer.GetAsync())
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	numMsgs := 5
+	for i := 0; i < numMsgs; i++ {
+		errSend := producer.SendString(queue, "Sent asynchronously")
+		assert.Nil(t, errSend)
+	}
+
+	// The outcome is checked half a second after the first message was sent,
+	// without the application needing to make any further calls.
+	time.Sleep(800 * time.Millisecond)
+
+	// Errors that are found straight away are returned by Send instead.
+	errSend := producer.SendString(context.CreateQueue("DEV.QUEUE.DOES.NOT.EXIST"), "Not sent")
+	assert.NotNil(t, errSend)
+	if errSend != nil {
+		assert.Equal(t, "2085", errSend.GetErrorCode())
+	}
+
+	listener.mutex.Lock()
+	assert.Equal(t, numMsgs, len(listener.completed))
+	assert.Empty(t, listener.failed)
+	listener.mutex.Unlock()
+
+	// Sends are synchronous again once the listener is removed.
+	producer.SetAsync(nil)
+	assert.Nil(t, producer.SendString(queue, "Sent synchronously"))
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	for i := 0; i < numMsgs+1; i++ {
+		rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+		assert.Nil(t, rcvErr)
+		assert.NotNil(t, rcvBody)
+	}
+
+	assert.Equal(t, numMsgs, listener.count())
+
+}
+
+// recordingListener is a CompletionListener that records the outcome of
+// each message that is sent.
+type recordingListener struct {
+	mutex     sync.Mutex
+	completed []jms20subset.Message
+	failed    []jms20subset.JMSException
+}
+
+func (listener *recordingListener) OnCompletion(msg jms20subset.Message) {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	listener.completed = append(listener.completed, msg)
+}
+
+func (listener *recordingListener) OnException(msg jms20subset.Message, err jms20subset.JMSException) {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	listener.failed = append(listener.failed, err)
+}
+
+func (listener *recordingListener) count() int {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	return len(listener.completed) + len(listener.failed)
+}
+
+/*
+ * Test that a CompletionListener can send further messages.
+ */
+func TestAsyncSendFromListener(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+
+	// The listener sends a follow-up message for each message that was sent,
+	// which must not wait for the outcome of the original message to finish
+	// being reported.
+	followUps := &recordingListener{}
+	followUpProducer := context.CreateProducer().SetAsync(followUps)
+	listener := &sendingListener{producer: followUpProducer, queue: queue}
+	producer := context.CreateProducer().SetAsync(listener)
+
+	errSend := producer.SendString(queue, "Sent asynchronously")
+	assert.Nil(t, errSend)
+
+	// The listener is called on the timer that checks the outcome, and the
+	// follow-up message that it sends is checked by the next timer.
+	time.Sleep(800 * time.Millisecond)
+	assert.Nil(t, listener.getErr())
+
+	time.Sleep(800 * time.Millisecond)
+	assert.Equal(t, 1, followUps.count())
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	for i := 0; i < 2; i++ {
+		rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+		assert.Nil(t, rcvErr)
+		assert.NotNil(t, rcvBody)
+	}
+
+}
+
+// sendingListener is a CompletionListener that sends another message each
+// time that it is told about the outcome of a message.
+type sendingListener struct {
+	producer jms20subset.JMSProducer
+	queue    jms20subset.Queue
+	mutex    sync.Mutex
+	err      jms20subset.JMSException
+}
+
+func (listener *sendingListener) OnCompletion(msg jms20subset.Message) {
+	err := listener.producer.SendString(listener.queue, "Sent from a listener")
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	listener.err = err
+}
+
+func (listener *sendingListener) OnException(msg jms20subset.Message, err jms20subset.JMSException) {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	listener.err = err
+}
+
+func (listener *sendingListener) getErr() jms20subset.JMSException {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	return listener.err
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+/*
+ * Test sending a batch of messages, both with and without units of work.
+ */
+func TestSendBatch(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	producer := context.CreateProducer()
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	for _, options := range []jms20subset.BatchOptions{
+		{},
+		{Transacted: true},
+		{Transacted: true, ChunkSize: 2},
+	} {
+
+		msgs := make([]jms20subset.Message, 5)
+		for i := range msgs {
+			msgs[i] = context.CreateTextMessageWithString("Batch message " + strconv.Itoa(i))
+		}
+
+		results, errSend := producer.SendBatch(queue, msgs, options)
+		assert.Nil(t, errSend)
+		assert.Equal(t, len(msgs), len(results))
+
+		// Every message is sent, in order, with its own message ID.
+		for i, result := range results {
+			assert.Nil(t, result.Err)
+			assert.NotEqual(t, "", result.MessageID)
+
+			rcvMsg, rcvErr := consumer.ReceiveNoWait()
+			assert.Nil(t, rcvErr)
+			assert.NotNil(t, rcvMsg)
+			if rcvMsg != nil {
+				assert.Equal(t, result.MessageID, rcvMsg.GetJMSMessageID())
+				textMsg, ok := rcvMsg.(jms20subset.TextMessage)
+				assert.True(t, ok)
+				if ok {
+					assert.Equal(t, "Batch message "+strconv.Itoa(i), *textMsg.GetText())
+				}
+			}
+		}
+	}
+
+}
+
+/*
+ * Test that a batch reports the messages that could not be sent.
+ */
+func TestSendBatchFailure(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	producer := context.CreateProducer()
+	msgs := []jms20subset.Message{
+		context.CreateTextMessageWithString("Not sent 1"),
+		context.CreateTextMessageWithString("Not sent 2"),
+	}
+
+	// The queue does not exist, so none of the messages are sent.
+	results, errSend := producer.SendBatch(context.CreateQueue("DEV.QUEUE.MISSING"), msgs,
+		jms20subset.BatchOptions{Transacted: true})
+	assert.NotNil(t, errSend)
+	if errSend != nil {
+		assert.Equal(t, "2085", errSend.GetErrorCode())
+	}
+	assert.Equal(t, len(msgs), len(results))
+	for _, result := range results {
+		assert.NotNil(t, result.Err)
+		assert.Equal(t, "", result.MessageID)
+	}
+
+	// An invalid chunk size is rejected.
+	_, errSend = producer.SendBatch(context.CreateQueue("DEV.QUEUE.1"), msgs,
+		jms20subset.BatchOptions{Transacted: true, ChunkSize: -1})
+	assert.NotNil(t, errSend)
+	if errSend != nil {
+		assert.Equal(t, "InvalidBatchOptions", errSend.GetErrorCode())
+	}
+
+}
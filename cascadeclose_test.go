@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test that closing a context also closes the consumers and producers that
+ * were created from it, and that they return an error if they are used after
+ * the context has been closed.
+ */
+func TestCascadeClose(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	// Creates a connection to the queue manager.
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+
+	// Equivalent to a JNDI lookup or other declarative definition
+	queue := context.CreateQueue("DEV.QUEUE.1")
+
+	// Set up a producer and a consumer, but do not close them explicitly.
+	producer := context.CreateProducer()
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+
+	// Check that the objects work while the context is open.
+	errSend := producer.SendString(queue, "Cascade close message")
+	assert.Nil(t, errSend)
+	rcvBody, errRcv := consumer.ReceiveStringBodyNoWait()
+	assert.Nil(t, errRcv)
+	assert.NotNil(t, rcvBody)
+
+	// Closing the context should close the child objects as well.
+	context.Close()
+
+	errSend2 := producer.SendString(queue, "Should not be sent")
+	assert.NotNil(t, errSend2)
+	assert.Equal(t, "IllegalStateException", errSend2.GetErrorCode())
+
+	_, errRcv2 := consumer.ReceiveNoWait()
+	assert.NotNil(t, errRcv2)
+	assert.Equal(t, "IllegalStateException", errRcv2.GetErrorCode())
+
+	_, errCons := context.CreateConsumer(queue)
+	assert.NotNil(t, errCons)
+	assert.Equal(t, "IllegalStateException", errCons.GetErrorCode())
+
+	// Closing the objects a second time should be harmless.
+	consumer.Close()
+	context.Close()
+
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test the ability to read and validate a JSON CCDT before using it to
+ * connect to a queue manager.
+ */
+func TestParseCCDT(t *testing.T) {
+
+	ccdt, err := mqjms.ParseCCDT("./config-samples/ccdt.json")
+	assert.Nil(t, err)
+	assert.Empty(t, ccdt.Validate())
+
+	// List the channels that are defined in the CCDT.
+	assert.Equal(t, []string{"DEV.APP.SVRCONN", "DEV.APP.SVRCONN"}, ccdt.ChannelNames())
+
+	// Find the channel that would be used to connect to a queue manager.
+	qm1Channels := ccdt.ChannelsForQueueManager("QM1")
+	assert.Equal(t, 1, len(qm1Channels))
+	assert.Equal(t, "myqm1-a.myserver.com(1414),myqm1-b.myserver.com(1414)", qm1Channels[0].GetConnectionNameList())
+	assert.Equal(t, "ANY_TLS12_OR_HIGHER", qm1Channels[0].TransmissionSecurity.CipherSpecification)
+
+	// The "*GROUP" syntax selects the channels of a queue manager group.
+	assert.Equal(t, 1, len(ccdt.ChannelsForQueueManager("*QM2")))
+
+}
+
+/*
+ * Test that problems with the channel definitions in a CCDT are reported.
+ */
+func TestValidateInvalidCCDT(t *testing.T) {
+
+	content := `{
+		"channel": [
+			{ "name": "", "type": "clientConnection", "clientConnection": { "connection": [ { "host": "", "port": 99999 } ] } },
+			{ "name": "MY.SERVER.CHANNEL", "type": "serverConnection" }
+		]
+	}`
+
+	ccdt, err := mqjms.ParseCCDTFromBytes([]byte(content), "inline")
+	assert.Nil(t, err)
+
+	errs := ccdt.Validate()
+	assert.Equal(t, 4, len(errs))
+
+	// A file that is not JSON is rejected.
+	_, err2 := mqjms.ParseCCDTFromBytes([]byte{0x00, 0x01, 0x02}, "AMQCLCHL.TAB")
+	assert.NotNil(t, err2)
+
+}
+
+/*
+ * Illustrate the configuration of a ConnectionFactory that reads its channel
+ * definition from a CCDT.
+ */
+func TestConnFactoryWithCCDT(t *testing.T) {
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:  "*QM1",
+		CCDTURL: "./config-samples/ccdt.json",
+	}
+
+	assert.NotNil(t, cf)
+
+}
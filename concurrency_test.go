@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+/*
+ * Test that a single context and producer can be shared by multiple goroutines
+ * without the calls failing because the connection is already in use.
+ */
+func TestConcurrentSendOnSharedContext(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	// Creates a connection to the queue manager, using defer to close it automatically
+	// at the end of the function (if it was created successfully)
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	// Equivalent to a JNDI lookup or other declarative definition
+	queue := context.CreateQueue("DEV.QUEUE.1")
+
+	// Share one producer between all of the goroutines.
+	producer := context.CreateProducer().SetDeliveryMode(jms20subset.DeliveryMode_NON_PERSISTENT)
+
+	numGoroutines := 5
+	msgsPerGoroutine := 10
+
+	var wg sync.WaitGroup
+	errs := make(chan jms20subset.JMSException, numGoroutines*msgsPerGoroutine)
+
+	for g := 0; g < numGoroutines; g++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for i := 0; i < msgsPerGoroutine; i++ {
+				err := producer.SendString(queue, "Goroutine "+strconv.Itoa(id)+" message "+strconv.Itoa(i))
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Fail(t, "Unexpected error from concurrent send", err.GetReason())
+	}
+
+	// Receive all of the messages to clean up the queue.
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	for i := 0; i < numGoroutines*msgsPerGoroutine; i++ {
+		rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+		assert.Nil(t, rcvErr)
+		assert.NotNil(t, rcvBody)
+	}
+
+	// There should be no more messages.
+	finalMsg, finalErr := consumer.ReceiveNoWait()
+	assert.Nil(t, finalErr)
+	assert.Nil(t, finalMsg)
+
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+ * Test loading named profiles from a YAML or JSON configuration file.
+ */
+func TestLoadConnectionFactory(t *testing.T) {
+
+	cf, err := mqjms.LoadConnectionFactory("./config-samples/connection_profiles.yaml", "prod-eu")
+	assert.Nil(t, err)
+	assert.Equal(t, mqjms.ConnectionFactoryImpl{
+		QMName:                 "QM1",
+		ConnectionNameList:     "eu-host1.myserver.com(1414),eu-host2.myserver.com(1414)",
+		ChannelName:            "APP.SVRCONN",
+		UserName:               "app",
+		Password:               "passw0rd",
+		TLSCipherSpec:          "ANY_TLS12",
+		TLSClientAuth:          mqjms.TLSClientAuth_REQUIRED,
+		KeyRepository:          "/var/mqm/ssl/key",
+		CertificateLabel:       "myCert",
+		ClientReconnectOptions: mqjms.ClientReconnect_QMGR,
+		ClientReconnectTimeout: 300,
+	}, cf)
+
+	ccdtCF, err2 := mqjms.LoadConnectionFactory("./config-samples/connection_profiles.yaml", "prod-us")
+	assert.Nil(t, err2)
+	assert.Equal(t, "*USGROUP", ccdtCF.QMName)
+	assert.Equal(t, "https://config.myserver.com/ccdt.json", ccdtCF.CCDTURL)
+
+	// The same schema can be used in JSON.
+	jsonCF, err3 := mqjms.LoadConnectionFactory("./config-samples/connection_profiles.json", "local")
+	assert.Nil(t, err3)
+	assert.Equal(t, mqjms.TransportType_BINDINGS, jsonCF.TransportType)
+
+	// A profile must be chosen if there is more than one.
+	_, err4 := mqjms.LoadConnectionFactory("./config-samples/connection_profiles.json", "")
+	assert.NotNil(t, err4)
+	assert.Equal(t, "A profile must be specified for ./config-samples/connection_profiles.json,"+
+		" which contains the profiles dev, local", err4.Error())
+
+	_, err5 := mqjms.LoadConnectionFactory("./config-samples/connection_profiles.yaml", "prod-ap")
+	assert.NotNil(t, err5)
+	assert.Equal(t, "Unable to find profile prod-ap in ./config-samples/connection_profiles.yaml,"+
+		" which contains the profiles dev, local, prod-eu, prod-us", err5.Error())
+
+}
+
+/*
+ * Test that a configuration file that does not match the schema is rejected
+ * with a description of every problem.
+ */
+func TestLoadConnectionFactoryErrors(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "mqjms")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Every problem in the profile is reported at once.
+	invalidFile := filepath.Join(dir, "invalid.yaml")
+	ioutil.WriteFile(invalidFile, []byte(`
+profiles:
+  broken:
+    queueManagerName: QM1
+    listenerPort: 70000
+    password: secret
+    tlsClientAuth: OPTIONAL
+    clientReconnect: always
+`), 0600)
+
+	_, err1 := mqjms.LoadConnectionFactory(invalidFile, "broken")
+	assert.NotNil(t, err1)
+	assert.Equal(t, "Invalid profile broken in "+invalidFile+": "+
+		"applicationChannelName must be specified unless ccdtUrl is used; "+
+		"clientReconnect must be one of asdef, disabled, reconnect or qmgr, not \"always\"; "+
+		"listenerPort must be between 1 and 65535, not 70000; "+
+		"one of hostname, connectionNameList or ccdtUrl must be specified; "+
+		"tlsClientAuth must be NONE or REQUIRED, not \"OPTIONAL\"; "+
+		"username must be specified when password is specified", err1.Error())
+
+	// Attributes that are not part of the schema are rejected, in both formats.
+	unknownYAML := filepath.Join(dir, "unknown.yaml")
+	ioutil.WriteFile(unknownYAML, []byte("profiles:\n  dev:\n    hostnme: localhost\n"), 0600)
+	_, err2 := mqjms.LoadConnectionFactory(unknownYAML, "dev")
+	assert.NotNil(t, err2)
+	assert.Contains(t, err2.Error(), "hostnme")
+
+	unknownJSON := filepath.Join(dir, "unknown.json")
+	ioutil.WriteFile(unknownJSON, []byte(`{"profiles": {"dev": {"hostnme": "localhost"}}}`), 0600)
+	_, err3 := mqjms.LoadConnectionFactory(unknownJSON, "dev")
+	assert.NotNil(t, err3)
+	assert.Contains(t, err3.Error(), "hostnme")
+
+	// Values of the wrong type are rejected.
+	wrongType := filepath.Join(dir, "wrongtype.json")
+	ioutil.WriteFile(wrongType, []byte(`{"profiles": {"dev": {"listenerPort": "1414"}}}`), 0600)
+	_, err4 := mqjms.LoadConnectionFactory(wrongType, "dev")
+	assert.NotNil(t, err4)
+	assert.Contains(t, err4.Error(), "listenerPort")
+
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test the ability to populate a ConnectionFactory with a list of connection
+ * names, for example to connect to a multi-instance queue manager.
+ */
+func TestConnectionNameList(t *testing.T) {
+
+	// Build the connection name list from an ordered list of hosts and ports.
+	connNames, err := mqjms.CreateConnectionNameList(
+		[]string{"myqm1-a.myserver.com", "myqm1-b.myserver.com"}, []int{1414, 1415})
+	assert.Nil(t, err)
+	assert.Equal(t, "myqm1-a.myserver.com(1414),myqm1-b.myserver.com(1415)", connNames)
+
+	// The number of hosts and ports must match.
+	_, err2 := mqjms.CreateConnectionNameList([]string{"myqm1-a.myserver.com"}, []int{1414, 1415})
+	assert.NotNil(t, err2)
+
+	// Load the list from a JSON file.
+	cf, cfErr := mqjms.CreateConnectionFactoryFromJSON("./config-samples/connection_info_multi_instance.json",
+		"./config-samples/applicationApiKey.json")
+	assert.Nil(t, cfErr)
+	assert.Equal(t, "QM1", cf.QMName)
+	assert.Equal(t, "myqm1-a.myserver.com(1414),myqm1-b.myserver.com(1414)", cf.ConnectionNameList)
+	assert.Equal(t, "", cf.Hostname)
+	assert.Equal(t, "DEV.APP.SVRCONN", cf.ChannelName)
+
+}
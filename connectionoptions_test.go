@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test connecting with an application name and channel attributes that are
+ * set on the ConnectionFactory.
+ */
+func TestConnectionOptions(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	// The application name is shown in the APPLTAG of DISPLAY CONN.
+	cf.ApplicationName = "connectionoptions_test"
+	cf.MaxMsgLength = 1048576
+	cf.KeepAliveInterval = mqjms.KeepAliveInterval_AUTO
+	cf.SharingConversations = mqjms.SharingConversations_NONE
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	errSend := context.CreateProducer().SendString(queue, "Sent with connection options")
+	assert.Nil(t, errSend)
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvBody)
+
+}
+
+/*
+ * Test the validation of the application name and channel attributes.
+ */
+func TestConnectionOptionsValidate(t *testing.T) {
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:               "QM1",
+		Hostname:             "localhost",
+		PortNumber:           1414,
+		ChannelName:          "DEV.APP.SVRCONN",
+		ApplicationName:      "payments-service",
+		MaxMsgLength:         104857600,
+		KeepAliveInterval:    mqjms.KeepAliveInterval_AUTO,
+		SharingConversations: 1,
+	}
+	assert.Empty(t, cf.Validate())
+
+	// The options can be set using a URI.
+	uriCF, err := mqjms.CreateConnectionFactoryFromURI(cf.URI())
+	assert.Nil(t, err)
+	assert.Equal(t, cf, uriCF)
+
+	badCF := mqjms.ConnectionFactoryImpl{
+		QMName:               "QM1",
+		Hostname:             "localhost",
+		PortNumber:           1414,
+		ChannelName:          "DEV.APP.SVRCONN",
+		ApplicationName:      "an application name that is far too long",
+		MaxMsgLength:         104857601,
+		KeepAliveInterval:    100000,
+		SharingConversations: -2,
+	}
+
+	assert.Equal(t, []string{
+		"ApplicationName must not be longer than 28 characters",
+		"MaxMsgLength must be between 0 and 104857600, not 104857601",
+		"KeepAliveInterval must be KeepAliveInterval_AUTO or between 0 and 99999, not 100000",
+		"SharingConversations must be SharingConversations_NONE or between 0 and 999999999, not -2",
+	}, errorStrings(badCF.Validate()))
+
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+/*
+ * Test that CreateContext fails quickly with a distinct error code if the
+ * hostname of the queue manager does not exist.
+ */
+func TestConnectTimeoutUnknownHost(t *testing.T) {
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:         "QM1",
+		Hostname:       "nosuchhost.invalid",
+		PortNumber:     1414,
+		ChannelName:    "DEV.APP.SVRCONN",
+		ConnectTimeout: 5000,
+	}
+
+	start := time.Now()
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, context)
+	assert.NotNil(t, ctxErr)
+	assert.Equal(t, mqjms.ErrorCode_UNKNOWN_HOST, ctxErr.GetErrorCode())
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+}
+
+/*
+ * Test that CreateContext fails quickly with a distinct error code if nothing
+ * is listening on the port of the queue manager.
+ */
+func TestConnectTimeoutConnectionRefused(t *testing.T) {
+
+	// Find a port that nothing is listening on.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:             "QM1",
+		ConnectionNameList: "127.0.0.1(" + strconv.Itoa(port) + ")",
+		ChannelName:        "DEV.APP.SVRCONN",
+		ConnectTimeout:     5000,
+	}
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, context)
+	assert.NotNil(t, ctxErr)
+	assert.Equal(t, mqjms.ErrorCode_CONNECTION_REFUSED, ctxErr.GetErrorCode())
+	assert.Contains(t, ctxErr.GetReason(), "127.0.0.1:"+strconv.Itoa(port)+" refused the connection")
+
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"context"
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test the creation of additional contexts that share the connection of an
+ * existing context.
+ */
+func TestCreateContextFromContext(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	// Creates a connection to the queue manager.
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	assert.Equal(t, jms20subset.JMSContext_AUTO_ACKNOWLEDGE, context.GetSessionMode())
+
+	// Create a second context that shares the same connection.
+	context2, ctxErr2 := context.CreateContext(jms20subset.JMSContext_AUTO_ACKNOWLEDGE)
+	assert.Nil(t, ctxErr2)
+	assert.NotNil(t, context2)
+
+	// Session modes that are not supported are rejected.
+	_, ctxErr3 := context.CreateContext(jms20subset.JMSContext_SESSION_TRANSACTED)
+	assert.NotNil(t, ctxErr3)
+	assert.Equal(t, "UnsupportedSessionMode", ctxErr3.GetErrorCode())
+
+	// Send a message using the first context and receive it using the second.
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	msgBody := "Message sent on the first context"
+	errSend := context.CreateProducer().SendString(queue, msgBody)
+	assert.Nil(t, errSend)
+
+	// Closing the first context should not affect the second one.
+	context.Close()
+
+	consumer, conErr := context2.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+
+	rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvBody)
+	assert.Equal(t, msgBody, *rcvBody)
+
+	// A new context cannot be created from a context that has been closed.
+	_, ctxErr4 := context.CreateContext(jms20subset.JMSContext_AUTO_ACKNOWLEDGE)
+	assert.NotNil(t, ctxErr4)
+	assert.Equal(t, "IllegalStateException", ctxErr4.GetErrorCode())
+
+	// Closing the last context disconnects the connection.
+	context2.Close()
+
+}
+
+/*
+ * Test that a context created from another context uses the existing
+ * connection rather than connecting and authenticating again.
+ */
+func TestCreateContextSharesConnection(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	// Count the number of times that the credentials are asked for, which
+	// happens each time that a connection is made.
+	provider := &countingCredentialsProvider{user: cf.UserName, secret: cf.Password}
+	cf.UserName = ""
+	cf.Password = ""
+	cf.CredentialsProvider = provider
+	cf.ClientReconnectOptions = mqjms.ClientReconnect_DISABLED
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+	assert.Equal(t, 1, provider.calls)
+
+	context2, ctxErr2 := context.CreateContext(jms20subset.JMSContext_AUTO_ACKNOWLEDGE)
+	assert.Nil(t, ctxErr2)
+	assert.Equal(t, 1, provider.calls)
+
+	// Both contexts can be used on the shared connection.
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	msgBody := "Message sent on the child context"
+	errSend := context2.CreateProducer().SendString(queue, msgBody)
+	assert.Nil(t, errSend)
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvBody)
+	assert.Equal(t, msgBody, *rcvBody)
+
+	context2.Close()
+
+}
+
+// countingCredentialsProvider supplies fixed credentials, and counts the
+// number of times that it has been asked for them.
+type countingCredentialsProvider struct {
+	user   string
+	secret string
+	calls  int
+}
+
+func (provider *countingCredentialsProvider) Credentials(ctx context.Context) (string, string, error) {
+	provider.calls++
+	return provider.user, provider.secret, nil
+}
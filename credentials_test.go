@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+ * Test reading credentials from files, such as a mounted Kubernetes secret,
+ * which are read again each time that they are needed.
+ */
+func TestFileCredentialsProvider(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "mqjms")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	provider := mqjms.FileCredentialsProvider{
+		UserNameFile: filepath.Join(dir, "username"),
+		PasswordFile: filepath.Join(dir, "password"),
+	}
+
+	ioutil.WriteFile(provider.UserNameFile, []byte("app\n"), 0600)
+	ioutil.WriteFile(provider.PasswordFile, []byte("passw0rd\n"), 0600)
+
+	user, secret, credsErr := provider.Credentials(context.Background())
+	assert.Nil(t, credsErr)
+	assert.Equal(t, "app", user)
+	assert.Equal(t, "passw0rd", secret)
+
+	// The rotated password is picked up the next time it is needed.
+	ioutil.WriteFile(provider.PasswordFile, []byte("n3wPassw0rd\n"), 0600)
+	_, secret2, credsErr2 := provider.Credentials(context.Background())
+	assert.Nil(t, credsErr2)
+	assert.Equal(t, "n3wPassw0rd", secret2)
+
+	// A missing file is reported as an error.
+	os.Remove(provider.PasswordFile)
+	_, _, credsErr3 := provider.Credentials(context.Background())
+	assert.NotNil(t, credsErr3)
+
+}
+
+/*
+ * Test reading credentials from environment variables.
+ */
+func TestEnvCredentialsProvider(t *testing.T) {
+
+	defer setTestEnv(map[string]string{"TEST_MQ_USER": "app", "TEST_MQ_PASSWORD": "passw0rd"})()
+
+	provider := mqjms.EnvCredentialsProvider{
+		UserNameVar: "TEST_MQ_USER",
+		PasswordVar: "TEST_MQ_PASSWORD",
+	}
+
+	user, secret, credsErr := provider.Credentials(context.Background())
+	assert.Nil(t, credsErr)
+	assert.Equal(t, "app", user)
+	assert.Equal(t, "passw0rd", secret)
+
+	missingProvider := mqjms.EnvCredentialsProvider{
+		UserNameVar: "TEST_MQ_USER",
+		PasswordVar: "TEST_MQ_NO_SUCH_VAR",
+	}
+
+	_, _, credsErr2 := missingProvider.Credentials(context.Background())
+	assert.NotNil(t, credsErr2)
+	assert.Equal(t, "Environment variable TEST_MQ_NO_SUCH_VAR is not set", credsErr2.Error())
+
+}
+
+// failingCredentialsProvider is a CredentialsProvider that is unable to
+// supply any credentials.
+type failingCredentialsProvider struct{}
+
+func (provider failingCredentialsProvider) Credentials(ctx context.Context) (string, string, error) {
+	return "", "", errors.New("secrets manager is unavailable")
+}
+
+/*
+ * Test that a failure to obtain credentials is reported by CreateContext, and
+ * that the password of a ConnectionFactory is not printed.
+ */
+func TestCredentialsProviderConnect(t *testing.T) {
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:                 "QM1",
+		Hostname:               "localhost",
+		PortNumber:             1414,
+		ChannelName:            "DEV.APP.SVRCONN",
+		CredentialsProvider:    failingCredentialsProvider{},
+		ClientReconnectOptions: mqjms.ClientReconnect_DISABLED,
+	}
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, context)
+	assert.NotNil(t, ctxErr)
+	assert.Equal(t, mqjms.ErrorCode_CREDENTIALS_UNAVAILABLE, ctxErr.GetErrorCode())
+	assert.Equal(t, "Unable to obtain credentials: secrets manager is unavailable", ctxErr.GetReason())
+
+	// Static credentials cannot be combined with a provider.
+	cf.UserName = "app"
+	assert.Equal(t, "UserName and Password must not be specified when a CredentialsProvider is specified",
+		cf.Validate()[0].Error())
+
+	// The MQ client would reconnect with the original credentials, so automatic
+	// reconnection must be disabled explicitly when using a provider, rather
+	// than being left to the channel definition.
+	cf.UserName = ""
+	reconnectErr := "ClientReconnectOptions must be ClientReconnect_DISABLED when a CredentialsProvider " +
+		"is specified, because the MQ client would reconnect using the original credentials"
+	for _, option := range []int{mqjms.ClientReconnect_AS_DEF, mqjms.ClientReconnect_RECONNECT, mqjms.ClientReconnect_QMGR} {
+		cf.ClientReconnectOptions = option
+		assert.Equal(t, reconnectErr, cf.Validate()[0].Error())
+	}
+
+	// The password is masked when the ConnectionFactory is printed.
+	cf.CredentialsProvider = nil
+	cf.Password = "passw0rd"
+	assert.NotContains(t, fmt.Sprintf("%+v", cf), "passw0rd")
+	assert.NotContains(t, fmt.Sprintf("%v", &cf), "passw0rd")
+
+}
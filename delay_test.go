@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+/*
+ * Test that a message sent with a delivery delay is held on the staging queue
+ * until it is due, and is then delivered with its original headers and
+ * properties.
+ */
+func TestDeliveryDelay(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+	cf.DeliveryDelayQueue = "DEV.QUEUE.3"
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	producer := context.CreateProducer().SetDeliveryDelay(1000)
+	assert.Equal(t, 1000, producer.GetDeliveryDelay())
+
+	msg := context.CreateTextMessageWithString("Delayed message")
+	msg.SetJMSCorrelationID("delayedCorrel")
+	msg.SetStringProperty("reminder", "first")
+
+	sendTime := time.Now().UnixNano() / 1000000
+	errSend := producer.Send(queue, msg)
+	assert.Nil(t, errSend)
+	assert.True(t, msg.GetJMSDeliveryTime() >= sendTime+1000)
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	// The message is not delivered before it is due.
+	scheduler := &mqjms.DelayScheduler{Factory: cf}
+	delivered, schedErr := scheduler.DeliverDue()
+	assert.Nil(t, schedErr)
+	assert.Equal(t, 0, delivered)
+
+	rcvMsg, rcvErr := consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.Nil(t, rcvMsg)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	delivered, schedErr = scheduler.DeliverDue()
+	assert.Nil(t, schedErr)
+	assert.Equal(t, 1, delivered)
+
+	rcvMsg, rcvErr = consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	if rcvMsg != nil {
+		assert.Equal(t, msg.GetJMSMessageID(), rcvMsg.GetJMSMessageID())
+		assert.Equal(t, "delayedCorrel", rcvMsg.GetJMSCorrelationID())
+		assert.Equal(t, "first", *rcvMsg.GetStringProperty("reminder"))
+		assert.Equal(t, msg.GetJMSDeliveryTime(), rcvMsg.GetJMSDeliveryTime())
+		assert.Equal(t, []string{"reminder"}, rcvMsg.GetPropertyNames())
+	}
+
+	// A scheduler running in the background delivers the message when it is due.
+	scheduler = &mqjms.DelayScheduler{Factory: cf, PollInterval: 100}
+	assert.Nil(t, scheduler.Start())
+	defer scheduler.Stop()
+
+	errSend = producer.SendWithOptions(queue, context.CreateTextMessageWithString("Delayed again"),
+		jms20subset.SendOptions{DeliveryDelay: 500})
+	assert.Nil(t, errSend)
+
+	rcvMsg, rcvErr = consumer.Receive(5000)
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	if rcvMsg != nil {
+		assert.True(t, time.Now().UnixNano()/1000000 >= rcvMsg.GetJMSDeliveryTime())
+	}
+
+}
+
+/*
+ * Test that a delivery delay is rejected if there is no staging queue, or if
+ * the staging queue does not exist.
+ */
+func TestDeliveryDelayWithoutQueue(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	errSend := context.CreateProducer().SetDeliveryDelay(1000).SendString(context.CreateQueue("DEV.QUEUE.1"), "Not sent")
+	assert.NotNil(t, errSend)
+	if errSend != nil {
+		assert.Equal(t, "UnsupportedDeliveryDelay", errSend.GetErrorCode())
+	}
+
+	_, schedErr := (&mqjms.DelayScheduler{Factory: cf}).DeliverDue()
+	assert.NotNil(t, schedErr)
+
+	// A message that could not be put to the staging queue is not given a
+	// delivery time.
+	cf.DeliveryDelayQueue = "DEV.QUEUE.DOES.NOT.EXIST"
+	context2, ctxErr2 := cf.CreateContext()
+	assert.Nil(t, ctxErr2)
+	if context2 != nil {
+		defer context2.Close()
+	}
+
+	msg := context2.CreateTextMessageWithString("Not sent")
+	errSend = context2.CreateProducer().SetDeliveryDelay(1000).Send(context2.CreateQueue("DEV.QUEUE.1"), msg)
+	assert.NotNil(t, errSend)
+	assert.Equal(t, int64(0), msg.GetJMSDeliveryTime())
+
+}
+
+/*
+ * Test that a delayed message that cannot be delivered is moved to the
+ * dead-letter queue after the maximum number of attempts, instead of being
+ * tried again forever.
+ */
+func TestDeliveryDelayUndeliverable(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+	cf.DeliveryDelayQueue = "DEV.QUEUE.3"
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	errSend := context.CreateProducer().SetDeliveryDelay(100).
+		SendString(context.CreateQueue("DEV.QUEUE.DOES.NOT.EXIST"), "Undeliverable message")
+	assert.Nil(t, errSend)
+	time.Sleep(200 * time.Millisecond)
+
+	scheduler := &mqjms.DelayScheduler{
+		Factory:             cf,
+		MaxDeliveryAttempts: 2,
+		DeadLetterQueue:     "DEV.DEAD.LETTER.QUEUE",
+	}
+
+	// Each failed attempt is reported, until the message has been tried the
+	// maximum number of times.
+	for i := 0; i < 2; i++ {
+		delivered, schedErr := scheduler.DeliverDue()
+		assert.Equal(t, 0, delivered)
+		assert.NotNil(t, schedErr)
+		if schedErr != nil {
+			assert.Equal(t, "2085", schedErr.GetErrorCode())
+		}
+	}
+
+	delivered, schedErr := scheduler.DeliverDue()
+	assert.Equal(t, 0, delivered)
+	assert.NotNil(t, schedErr)
+	if schedErr != nil {
+		assert.Equal(t, "2362", schedErr.GetErrorCode())
+	}
+
+	// The message is no longer on the staging queue.
+	delivered, schedErr = scheduler.DeliverDue()
+	assert.Equal(t, 0, delivered)
+	assert.Nil(t, schedErr)
+
+	consumer, conErr := context.CreateConsumer(context.CreateQueue("DEV.DEAD.LETTER.QUEUE"))
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvMsg, rcvErr := consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+/*
+ * Test the creation of a ConnectionFactory from environment variables.
+ */
+func TestConnectionFactoryFromEnv(t *testing.T) {
+
+	defer setTestEnv(map[string]string{
+		"MYAPP_QMNAME":                   "QM1",
+		"MYAPP_HOSTNAME":                 "localhost",
+		"MYAPP_PORT":                     "1415",
+		"MYAPP_CHANNEL":                  "DEV.APP.SVRCONN",
+		"MYAPP_USERNAME":                 "app",
+		"MYAPP_PASSWORD":                 "passw0rd",
+		"MYAPP_TLS_CIPHERSPEC":           "ANY_TLS12",
+		"MYAPP_TLS_CLIENTAUTH":           "REQUIRED",
+		"MYAPP_KEY_REPOSITORY":           "/var/mqm/ssl/key",
+		"MYAPP_CERTIFICATE_LABEL":        "myCert",
+		"MYAPP_CLIENT_RECONNECT":         "qmgr",
+		"MYAPP_CLIENT_RECONNECT_TIMEOUT": "60",
+	})()
+
+	cf, err := mqjms.CreateConnectionFactoryFromEnv("MYAPP")
+	assert.Nil(t, err)
+
+	assert.Equal(t, mqjms.ConnectionFactoryImpl{
+		QMName:                 "QM1",
+		Hostname:               "localhost",
+		PortNumber:             1415,
+		ChannelName:            "DEV.APP.SVRCONN",
+		UserName:               "app",
+		Password:               "passw0rd",
+		TLSCipherSpec:          "ANY_TLS12",
+		TLSClientAuth:          mqjms.TLSClientAuth_REQUIRED,
+		KeyRepository:          "/var/mqm/ssl/key",
+		CertificateLabel:       "myCert",
+		ClientReconnectOptions: mqjms.ClientReconnect_QMGR,
+		ClientReconnectTimeout: 60,
+	}, cf)
+
+	// Invalid values are reported with the name of the variable.
+	defer setTestEnv(map[string]string{"MYAPP_PORT": "14l4"})()
+	_, err2 := mqjms.CreateConnectionFactoryFromEnv("MYAPP")
+	assert.NotNil(t, err2)
+	assert.Equal(t, "Invalid value \"14l4\" for environment variable MYAPP_PORT, expected a port number between 1 and 65535", err2.Error())
+
+}
+
+/*
+ * Test that the standard MQ client environment variables are honoured.
+ */
+func TestConnectionFactoryFromStandardMQEnv(t *testing.T) {
+
+	defer setTestEnv(map[string]string{
+		"MQSERVER":     "DEV.APP.SVRCONN/TCP/myhost(1416)",
+		"MQSSLKEYR":    "/var/mqm/ssl/key",
+		"MQJMS_QMNAME": "QM1",
+	})()
+
+	cf, err := mqjms.CreateConnectionFactoryFromEnv("")
+	assert.Nil(t, err)
+	assert.Equal(t, "QM1", cf.QMName)
+	assert.Equal(t, "DEV.APP.SVRCONN", cf.ChannelName)
+	assert.Equal(t, "myhost", cf.Hostname)
+	assert.Equal(t, 1416, cf.PortNumber)
+	assert.Equal(t, "/var/mqm/ssl/key", cf.KeyRepository)
+
+	// A list of connection names is used for a multi-instance queue manager.
+	defer setTestEnv(map[string]string{"MQSERVER": "DEV.APP.SVRCONN/TCP/host1(1414),host2(1414)"})()
+	cf2, err2 := mqjms.CreateConnectionFactoryFromEnv("")
+	assert.Nil(t, err2)
+	assert.Equal(t, "host1(1414),host2(1414)", cf2.ConnectionNameList)
+
+	// Prefixed variables take precedence over the standard variables.
+	defer setTestEnv(map[string]string{"MQJMS_CHANNEL": "OTHER.SVRCONN"})()
+	cf3, err3 := mqjms.CreateConnectionFactoryFromEnv("")
+	assert.Nil(t, err3)
+	assert.Equal(t, "OTHER.SVRCONN", cf3.ChannelName)
+
+	// A malformed MQSERVER value is rejected.
+	defer setTestEnv(map[string]string{"MQSERVER": "DEV.APP.SVRCONN/LU62/myhost"})()
+	_, err4 := mqjms.CreateConnectionFactoryFromEnv("")
+	assert.NotNil(t, err4)
+
+	// MQCHLLIB and MQCHLTAB identify a CCDT if MQSERVER is not set.
+	defer setTestEnv(map[string]string{"MQSERVER": "", "MQJMS_CHANNEL": "", "MQCHLLIB": "/var/mqm/ccdt",
+		"MQCHLTAB": "ccdt.json"})()
+	cf5, err5 := mqjms.CreateConnectionFactoryFromEnv("")
+	assert.Nil(t, err5)
+	assert.Equal(t, "/var/mqm/ccdt/ccdt.json", cf5.CCDTURL)
+
+}
+
+/*
+ * Test that a prefixed variable replaces the settings from the standard MQ
+ * variables that it would otherwise conflict with.
+ */
+func TestConnectionFactoryEnvPrecedence(t *testing.T) {
+
+	// Clear any standard variables that are set for the test run.
+	defer setTestEnv(map[string]string{"MQSERVER": "", "MQCCDTURL": "", "MQCHLLIB": "", "MQCHLTAB": "",
+		"MQJMS_QMNAME": "QM1"})()
+
+	// A connection name list replaces the host from MQSERVER.
+	restore := setTestEnv(map[string]string{
+		"MQSERVER":                   "DEV.APP.SVRCONN/TCP/myhost(1416)",
+		"MQJMS_CONNECTION_NAME_LIST": "host1(1414),host2(1414)",
+	})
+	cf, err := mqjms.CreateConnectionFactoryFromEnv("")
+	restore()
+	assert.Nil(t, err)
+	assert.Equal(t, "", cf.Hostname)
+	assert.Equal(t, "host1(1414),host2(1414)", cf.ConnectionNameList)
+	assert.Equal(t, "DEV.APP.SVRCONN", cf.ChannelName)
+	assert.Empty(t, cf.Validate())
+
+	// A hostname replaces the connection name list from MQSERVER.
+	restore = setTestEnv(map[string]string{
+		"MQSERVER":       "DEV.APP.SVRCONN/TCP/host1(1414),host2(1414)",
+		"MQJMS_HOSTNAME": "myhost",
+		"MQJMS_PORT":     "1414",
+	})
+	cf, err = mqjms.CreateConnectionFactoryFromEnv("")
+	restore()
+	assert.Nil(t, err)
+	assert.Equal(t, "myhost", cf.Hostname)
+	assert.Equal(t, "", cf.ConnectionNameList)
+	assert.Empty(t, cf.Validate())
+
+	// A hostname and channel replace a CCDT from MQCHLLIB.
+	restore = setTestEnv(map[string]string{
+		"MQCHLLIB":       "/var/mqm/ccdt",
+		"MQJMS_HOSTNAME": "myhost",
+		"MQJMS_PORT":     "1414",
+		"MQJMS_CHANNEL":  "DEV.APP.SVRCONN",
+	})
+	cf, err = mqjms.CreateConnectionFactoryFromEnv("")
+	restore()
+	assert.Nil(t, err)
+	assert.Equal(t, "", cf.CCDTURL)
+	assert.Equal(t, "myhost", cf.Hostname)
+	assert.Empty(t, cf.Validate())
+
+	// A channel replaces a CCDT from MQCCDTURL, which then needs a host.
+	restore = setTestEnv(map[string]string{
+		"MQCCDTURL":     "file:///var/mqm/ccdt/ccdt.json",
+		"MQJMS_CHANNEL": "DEV.APP.SVRCONN",
+	})
+	cf, err = mqjms.CreateConnectionFactoryFromEnv("")
+	restore()
+	assert.Nil(t, err)
+	assert.Equal(t, "", cf.CCDTURL)
+	assert.Equal(t, "DEV.APP.SVRCONN", cf.ChannelName)
+
+	// A CCDT replaces the channel and host from MQSERVER.
+	restore = setTestEnv(map[string]string{
+		"MQSERVER":       "DEV.APP.SVRCONN/TCP/myhost(1416)",
+		"MQJMS_CCDT_URL": "file:///var/mqm/ccdt/ccdt.json",
+	})
+	cf, err = mqjms.CreateConnectionFactoryFromEnv("")
+	restore()
+	assert.Nil(t, err)
+	assert.Equal(t, "file:///var/mqm/ccdt/ccdt.json", cf.CCDTURL)
+	assert.Equal(t, "", cf.ChannelName)
+	assert.Equal(t, "", cf.Hostname)
+	assert.Empty(t, cf.Validate())
+
+}
+
+// setTestEnv sets the specified environment variables, and returns a function
+// that restores their original values at the end of the test.
+func setTestEnv(vars map[string]string) func() {
+
+	originals := make(map[string]*string)
+
+	for name, value := range vars {
+		if original, existed := os.LookupEnv(name); existed {
+			originals[name] = &original
+		} else {
+			originals[name] = nil
+		}
+		os.Setenv(name, value)
+	}
+
+	return func() {
+		for name, original := range originals {
+			if original != nil {
+				os.Setenv(name, *original)
+			} else {
+				os.Unsetenv(name)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+)
+
+/*
+ * Test the registration of an exception listener that is notified about
+ * problems with the connection to the queue manager.
+ *
+ * Note that this test does not cause the connection to fail, so the listener
+ * is not expected to be called.
+ */
+func TestExceptionListener(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	// Creates a connection to the queue manager.
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+
+	// Register a listener that an application would use to raise an alert or
+	// to rebuild its state when the connection fails.
+	listenerErr := context.SetExceptionListener(func(ex jms20subset.JMSException) {
+		log.Print("Connection problem reported: " + ex.GetReason())
+	})
+	assert.Nil(t, listenerErr)
+
+	// Check that the connection continues to work as normal.
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	errSend := context.CreateProducer().SendString(queue, "Message with a listener")
+	assert.Nil(t, errSend)
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvBody)
+
+	// The listener can be removed again.
+	assert.Nil(t, context.SetExceptionListener(nil))
+
+	context.Close()
+
+	// A listener cannot be registered once the context has been closed.
+	closedErr := context.SetExceptionListener(func(ex jms20subset.JMSException) {})
+	assert.NotNil(t, closedErr)
+	assert.Equal(t, "IllegalStateException", closedErr.GetErrorCode())
+
+}
+
+/*
+ * Test that each context created from a connection has its own exception
+ * listener.
+ */
+func TestExceptionListenerPerContext(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	// Creates a connection to the queue manager, using defer to close it automatically
+	// at the end of the function (if it was created successfully)
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	childContext, childErr := context.CreateContext(jms20subset.JMSContext_AUTO_ACKNOWLEDGE)
+	assert.Nil(t, childErr)
+
+	assert.Nil(t, context.SetExceptionListener(func(ex jms20subset.JMSException) {
+		log.Print("Parent context problem reported: " + ex.GetReason())
+	}))
+	assert.Nil(t, childContext.SetExceptionListener(func(ex jms20subset.JMSException) {
+		log.Print("Child context problem reported: " + ex.GetReason())
+	}))
+
+	// Closing the child context stops its listener, but the listener of the
+	// parent context is still registered.
+	childContext.Close()
+	closedErr := childContext.SetExceptionListener(nil)
+	assert.NotNil(t, closedErr)
+	assert.Equal(t, "IllegalStateException", closedErr.GetErrorCode())
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	errSend := context.CreateProducer().SendString(queue, "Message after closing the child context")
+	assert.Nil(t, errSend)
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+	rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvBody)
+
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test sending messages to more queues than the context keeps open, so that
+ * the least recently used queue handle is closed and later reopened.
+ */
+func TestHandleCache(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	cf.HandleCacheSize = 1
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue1 := context.CreateQueue("DEV.QUEUE.1")
+	queue2 := context.CreateQueue("DEV.QUEUE.2")
+	producer := context.CreateProducer()
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, producer.SendString(queue1, "Sent to the first queue"))
+		assert.Nil(t, producer.SendString(queue2, "Sent to the second queue"))
+	}
+
+	// A consumer can open a queue that is held open by the context.
+	for _, queue := range []jms20subset.Queue{queue1, queue2} {
+		consumer, conErr := context.CreateConsumer(queue)
+		assert.Nil(t, conErr)
+		if consumer == nil {
+			continue
+		}
+
+		for i := 0; i < 3; i++ {
+			rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+			assert.Nil(t, rcvErr)
+			assert.NotNil(t, rcvBody)
+		}
+
+		consumer.Close()
+	}
+
+	// Sending to a queue that doesn't exist still fails.
+	errSend := producer.SendString(context.CreateQueue("DEV.QUEUE.DOES.NOT.EXIST"), "Not sent")
+	assert.NotNil(t, errSend)
+	if errSend != nil {
+		assert.Equal(t, "2085", errSend.GetErrorCode())
+	}
+
+}
+
+/*
+ * Test the configuration of the handle cache size.
+ */
+func TestHandleCacheSizeValidate(t *testing.T) {
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:          "QM1",
+		Hostname:        "localhost",
+		PortNumber:      1414,
+		ChannelName:     "DEV.APP.SVRCONN",
+		HandleCacheSize: mqjms.HandleCacheSize_DISABLED,
+	}
+	assert.Empty(t, cf.Validate())
+
+	uriCF, err := mqjms.CreateConnectionFactoryFromURI(cf.URI())
+	assert.Nil(t, err)
+	assert.Equal(t, cf, uriCF)
+
+	cf.HandleCacheSize = -2
+	assert.Equal(t, []string{
+		"HandleCacheSize must not be negative, apart from HandleCacheSize_DISABLED (-1)",
+	}, errorStrings(cf.Validate()))
+
+}
@@ -0,0 +1,38 @@
+// Derived from the Eclipse Project for JMS, available at;
+//     https://github.com/eclipse-ee4j/jms-api
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package jms20subset
+
+// BatchOptions controls how a batch of messages is sent using
+// JMSProducer.SendBatch.
+type BatchOptions struct {
+
+	// If Transacted is true then the messages are sent in units of work, each
+	// of which is committed once all of its messages have been sent, so that
+	// either all or none of the messages in a unit of work are delivered.
+	// Otherwise each message is delivered as soon as it is sent.
+	Transacted bool
+
+	// The maximum number of messages in each unit of work when Transacted is
+	// true, which bounds the amount of uncommitted work held by the queue
+	// manager. The default of zero means that the whole batch is sent in a
+	// single unit of work.
+	ChunkSize int
+}
+
+// SendResult is the outcome of sending one of the messages in a batch.
+type SendResult struct {
+
+	// The JMSMessageID of the message, if it was sent successfully.
+	MessageID string
+
+	// The reason that the message was not sent, or nil if it was sent.
+	Err JMSException
+}
@@ -0,0 +1,28 @@
+// Derived from the Eclipse Project for JMS, available at;
+//     https://github.com/eclipse-ee4j/jms-api
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package jms20subset
+
+// CompletionListener is implemented by an application that sends messages
+// asynchronously, so that it can be told whether each message was sent
+// successfully once the messaging provider knows the outcome.
+//
+// The listener may be called on a different goroutine from the one that sent
+// the message. The outcome of any messages that are still outstanding is
+// reported when the context is closed.
+type CompletionListener interface {
+
+	// OnCompletion is called when a message has been sent successfully.
+	OnCompletion(msg Message)
+
+	// OnException is called when a message could not be sent, with the error
+	// that describes the problem.
+	OnException(msg Message, err JMSException)
+}
@@ -15,14 +15,39 @@ package jms20subset
 // objects so that it can send and receive messages.
 type JMSContext interface {
 
+	// CreateContext creates a new context that shares the connection to the
+	// messaging provider of this context, and has the specified session mode.
+	//
+	// This allows an application to create additional contexts without
+	// connecting and authenticating again, or needing access to the original
+	// ConnectionFactory. The connection remains open until the last context
+	// that uses it has been closed.
+	//
+	// Permitted arguments to this method include
+	// jms20subset.JMSContext_AUTO_ACKNOWLEDGE and
+	// jms20subset.JMSContext_DUPS_OK_ACKNOWLEDGE.
+	CreateContext(sessionMode int) (JMSContext, JMSException)
+
+	// GetSessionMode returns the session mode of this context, for example
+	// jms20subset.JMSContext_AUTO_ACKNOWLEDGE.
+	GetSessionMode() int
+
 	// CreateProducer creates a new producer object that can be used to configure
 	// and send messages.
 	//
-	// Note that the Destination object is always supplied when making the
-	// individual producer.Send calls, and not as part of creating the producer
-	// itself.
+	// Note that the Destination object is supplied when making the individual
+	// producer.Send calls, and not as part of creating the producer itself. Use
+	// CreateProducerForDestination to create a producer for one Destination.
 	CreateProducer() JMSProducer
 
+	// CreateProducerForDestination creates a new producer object that sends
+	// messages to the specified Destination, so that the Destination does not
+	// have to be supplied each time a message is sent.
+	//
+	// Messages are sent to the Destination of the producer when nil is passed
+	// as the Destination to the producer.Send calls.
+	CreateProducerForDestination(dest Destination) JMSProducer
+
 	// CreateConsumer creates a consumer for the specified Destination so that
 	// an application can receive messages from that Destination.
 	CreateConsumer(dest Destination) (JMSConsumer, JMSException)
@@ -54,6 +79,18 @@ type JMSContext interface {
 	// name and different parameters we must use a different function name.
 	CreateTextMessageWithString(txt string) TextMessage
 
+	// SetExceptionListener registers a function that is called when the
+	// messaging provider detects a problem with the connection asynchronously,
+	// for example when the connection is broken, the provider is shutting down
+	// or the client is reconnecting. This allows an application to find out
+	// about the problem even if it is not currently sending or receiving
+	// messages.
+	//
+	// Each context has its own listener, which is only told about problems
+	// with the connection used by that context. Passing nil removes the
+	// listener.
+	SetExceptionListener(listener func(JMSException)) JMSException
+
 	// Closes the connection to the messaging provider.
 	//
 	// Since the provider typically allocates significant resources on behalf of
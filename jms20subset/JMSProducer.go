@@ -17,7 +17,8 @@ package jms20subset
 type JMSProducer interface {
 
 	// Send a message to the specified Destination, using any message options
-	// that are defined on this JMSProducer.
+	// that are defined on this JMSProducer. If this JMSProducer was created for
+	// a Destination then nil can be passed instead.
 	Send(dest Destination, msg Message) JMSException
 
 	// Send a TextMessage with the specified body to the specified Destination
@@ -27,6 +28,29 @@ type JMSProducer interface {
 	// name and different parameters we must use a different function name.
 	SendString(dest Destination, body string) JMSException
 
+	// SendWithOptions sends a message to the specified Destination using the
+	// message options in the SendOptions, which apply to this message only.
+	// Any option that is not set in the SendOptions is taken from this
+	// JMSProducer, whose options are not changed.
+	SendWithOptions(dest Destination, msg Message, options SendOptions) JMSException
+
+	// SendBatch sends the messages to the specified Destination using the
+	// message options that are defined on this JMSProducer, opening the
+	// Destination only once for the whole batch.
+	//
+	// A SendResult is returned for each message, in the same order as the
+	// messages. If any of the messages was not sent then a JMSException is
+	// also returned, which has the error code of the first failure.
+	//
+	// Messages in a batch are always sent synchronously, even if a
+	// CompletionListener has been set using SetAsync.
+	SendBatch(dest Destination, msgs []Message, options BatchOptions) ([]SendResult, JMSException)
+
+	// GetDestination returns the Destination that was specified when this
+	// JMSProducer was created, or nil if the Destination is supplied each time
+	// that a message is sent.
+	GetDestination() Destination
+
 	// SetDeliveryMode sets the delivery mode of messages sent using this
 	// JMSProducer - for example whether a message is persistent or non-persistent.
 	//
@@ -52,4 +76,84 @@ type JMSProducer interface {
 	// GetTimeToLive returns the time to live (in milliseconds) that will be
 	// applied to messages that are sent using this JMSProducer.
 	GetTimeToLive() int
+
+	// SetDeliveryDelay sets the minimum time (in milliseconds) after a message
+	// is sent before it can be delivered to a consumer. A value of zero means
+	// that messages can be delivered straight away.
+	SetDeliveryDelay(deliveryDelay int) JMSProducer
+
+	// GetDeliveryDelay returns the delivery delay (in milliseconds) that will be
+	// applied to messages that are sent using this JMSProducer.
+	GetDeliveryDelay() int
+
+	// SetDisableMessageID gives a hint that the application does not need
+	// message IDs for the messages sent using this JMSProducer. A provider
+	// may ignore the hint, in which case messages have a message ID as normal.
+	SetDisableMessageID(value bool) JMSProducer
+
+	// GetDisableMessageID returns whether message IDs are disabled for this
+	// JMSProducer.
+	GetDisableMessageID() bool
+
+	// SetDisableMessageTimestamp gives a hint that the application does not
+	// need timestamps for the messages sent using this JMSProducer. A provider
+	// may ignore the hint, in which case messages have a timestamp as normal.
+	SetDisableMessageTimestamp(value bool) JMSProducer
+
+	// GetDisableMessageTimestamp returns whether message timestamps are
+	// disabled for this JMSProducer.
+	GetDisableMessageTimestamp() bool
+
+	// SetJMSCorrelationID sets the correlation ID of messages that are sent
+	// using this JMSProducer, unless the message has its own correlation ID.
+	SetJMSCorrelationID(correlID string) JMSProducer
+
+	// GetJMSCorrelationID returns the correlation ID that is set on this
+	// JMSProducer.
+	GetJMSCorrelationID() string
+
+	// SetJMSReplyTo sets the Destination to which replies to messages that are
+	// sent using this JMSProducer should be sent, unless the message has its
+	// own reply Destination.
+	SetJMSReplyTo(dest Destination) JMSProducer
+
+	// GetJMSReplyTo returns the reply Destination that is set on this
+	// JMSProducer.
+	GetJMSReplyTo() Destination
+
+	// SetJMSType sets the type of messages that are sent using this
+	// JMSProducer, unless the message has its own type.
+	SetJMSType(jmsType string) JMSProducer
+
+	// GetJMSType returns the message type that is set on this JMSProducer.
+	GetJMSType() string
+
+	// SetStringProperty, SetIntProperty, SetBooleanProperty and
+	// SetDoubleProperty set an application property on messages that are sent
+	// using this JMSProducer, unless the message has its own value for the
+	// property.
+	SetStringProperty(name string, value string) JMSProducer
+	SetIntProperty(name string, value int) JMSProducer
+	SetBooleanProperty(name string, value bool) JMSProducer
+	SetDoubleProperty(name string, value float64) JMSProducer
+
+	// GetPropertyNames returns the names of the application properties that are
+	// set on this JMSProducer.
+	GetPropertyNames() []string
+
+	// ClearProperties removes all of the application properties that are set
+	// on this JMSProducer.
+	ClearProperties() JMSProducer
+
+	// SetAsync makes subsequent sends using this JMSProducer asynchronous, so
+	// that Send returns without waiting for the provider to confirm that the
+	// message was sent. The outcome of each send is reported to the specified
+	// CompletionListener instead. Errors that are detected before the message
+	// is handed to the provider are returned from Send, and are not reported
+	// to the listener. Passing nil makes sends synchronous again.
+	SetAsync(listener CompletionListener) JMSProducer
+
+	// GetAsync returns the CompletionListener that is set on this JMSProducer,
+	// or nil if sends are synchronous.
+	GetAsync() CompletionListener
 }
@@ -47,4 +47,64 @@ type Message interface {
 	// Typical values returned by this method include
 	// jms20subset.DeliveryMode_PERSISTENT and jms20subset.DeliveryMode_NON_PERSISTENT
 	GetJMSDeliveryMode() int
+
+	// GetJMSDeliveryTime returns the earliest time at which the message can be
+	// delivered to a consumer, in milliseconds since the epoch. This is the time
+	// at which the message was sent plus any delivery delay of the producer.
+	GetJMSDeliveryTime() int64
+
+	// SetJMSType sets the type of the message, which is an application defined
+	// value that describes the content of the message.
+	SetJMSType(jmsType string) JMSException
+
+	// GetJMSType returns the type of the message, or an empty string if no type
+	// has been set.
+	GetJMSType() string
+
+	// SetStringProperty sets an application property of the message to a
+	// string value, replacing any existing value of the property.
+	SetStringProperty(name string, value string) JMSException
+
+	// GetStringProperty returns the value of an application property as a
+	// string, or nil if the message does not have the property. Properties of
+	// any type can be returned as a string.
+	GetStringProperty(name string) *string
+
+	// SetIntProperty sets an application property of the message to an
+	// integer value, replacing any existing value of the property.
+	SetIntProperty(name string, value int) JMSException
+
+	// GetIntProperty returns the value of an application property as an
+	// integer. An error is returned if the message does not have the property,
+	// or if its value cannot be converted to an integer.
+	GetIntProperty(name string) (int, JMSException)
+
+	// SetBooleanProperty sets an application property of the message to a
+	// boolean value, replacing any existing value of the property.
+	SetBooleanProperty(name string, value bool) JMSException
+
+	// GetBooleanProperty returns the value of an application property as a
+	// boolean, or false if the message does not have the property. An error is
+	// returned if the value cannot be converted to a boolean.
+	GetBooleanProperty(name string) (bool, JMSException)
+
+	// SetDoubleProperty sets an application property of the message to a
+	// floating point value, replacing any existing value of the property.
+	SetDoubleProperty(name string, value float64) JMSException
+
+	// GetDoubleProperty returns the value of an application property as a
+	// floating point number. An error is returned if the message does not have
+	// the property, or if its value cannot be converted to a number.
+	GetDoubleProperty(name string) (float64, JMSException)
+
+	// PropertyExists returns true if the message has an application property
+	// with the specified name.
+	PropertyExists(name string) bool
+
+	// GetPropertyNames returns the names of the application properties of the
+	// message.
+	GetPropertyNames() []string
+
+	// ClearProperties removes all of the application properties of the message.
+	ClearProperties() JMSException
 }
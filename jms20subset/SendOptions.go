@@ -0,0 +1,44 @@
+// Derived from the Eclipse Project for JMS, available at;
+//     https://github.com/eclipse-ee4j/jms-api
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package jms20subset
+
+// SendOptions contains the message options for a single message that is sent
+// using JMSProducer.SendWithOptions, so that a message can be sent with
+// different options without changing the options of a JMSProducer that may
+// be shared with other goroutines.
+//
+// An option is used if it has a non-zero value or if its Set field is true,
+// which allows a value of zero to be given explicitly (for example a Priority
+// of 0, or a TimeToLive of 0 to override the time to live of the producer).
+// Any other option uses the value that is set on the producer.
+type SendOptions struct {
+
+	// DeliveryMode_PERSISTENT or DeliveryMode_NON_PERSISTENT.
+	DeliveryMode    int
+	DeliveryModeSet bool
+
+	// The time to live of the message in milliseconds, where zero means that
+	// the message does not expire.
+	TimeToLive    int
+	TimeToLiveSet bool
+
+	// The priority of the message, from 0 (lowest) to 9 (highest). If no
+	// priority is given then the message has the default priority of the
+	// destination.
+	Priority    int
+	PrioritySet bool
+
+	// The number of milliseconds after the message is sent before it can be
+	// delivered to a consumer, where zero means that it can be delivered
+	// straight away.
+	DeliveryDelay    int
+	DeliveryDelaySet bool
+}
@@ -0,0 +1,31 @@
+// Derived from the Eclipse Project for JMS, available at;
+//     https://github.com/eclipse-ee4j/jms-api
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package jms20subset
+
+// Go doesn't allow constants in structs so the naming of this file is only for
+// logical grouping purposes. The constants are package scoped, but we use a
+// prefix to the naming in order to maintain similarity with Java JMS.
+
+// Used to configure a context so that work is carried out in a local
+// transaction, which is committed or rolled back by the application.
+const JMSContext_SESSION_TRANSACTED int = 0
+
+// Used to configure a context so that each message is acknowledged
+// automatically when it is received. This is the default.
+const JMSContext_AUTO_ACKNOWLEDGE int = 1
+
+// Used to configure a context so that the application acknowledges messages
+// explicitly after they have been received.
+const JMSContext_CLIENT_ACKNOWLEDGE int = 2
+
+// Used to configure a context so that messages are acknowledged lazily, which
+// may result in a message being delivered more than once.
+const JMSContext_DUPS_OK_ACKNOWLEDGE int = 3
@@ -0,0 +1,241 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang/ibmmq"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The number of asynchronous sends after which the outcome is checked with
+// the queue manager, which bounds the number of messages whose outcome is not
+// yet known to the application.
+const asyncSendCheckCount = 100
+
+// The time after the first unchecked asynchronous send after which the
+// outcome is checked.
+const asyncSendCheckInterval = 500 * time.Millisecond
+
+// asyncSendTracker keeps track of the messages that have been sent using a
+// connection handle with MQPMO_ASYNC_RESPONSE, and reports their outcome to
+// the CompletionListener of the producer that sent them.
+//
+// The outcome is checked (using MQSTAT) on a timer once asyncSendCheckInterval
+// has passed since the first unchecked send, as well as after every
+// asyncSendCheckCount sends and when a context using the connection is closed.
+// The connection handle was created with MQCNO_HANDLE_SHARE_BLOCK, so the
+// timer waits for any call that another goroutine is making with it.
+//
+// The queue manager only reports how many of the puts since the last check
+// failed, along with the queue and reason for the first failure, so the failed
+// messages are worked out from those where possible (see outcomes).
+type asyncSendTracker struct {
+	qMgr ibmmq.MQQueueManager
+
+	// Protects the attributes below, and is held while putting a message so
+	// that the result of each MQSTAT covers exactly the pending messages.
+	mutex   sync.Mutex
+	pending []asyncSend
+	timer   *time.Timer
+	closed  bool
+
+	// Outcomes that have been checked but not yet reported. Only one goroutine
+	// reports them at a time, so that listeners are called in the order that
+	// the messages were sent, and the mutex is not held while calling a
+	// listener so that the listener can send further messages.
+	reports   []asyncReport
+	reporting bool
+}
+
+// asyncSend is a message that was sent asynchronously, and whose outcome has
+// not yet been reported.
+type asyncSend struct {
+	msg      jms20subset.Message
+	queue    QueueImpl
+	listener jms20subset.CompletionListener
+}
+
+// asyncReport is the outcome of an asynchronous send, where failure is nil if
+// the message was sent successfully.
+type asyncReport struct {
+	send    asyncSend
+	failure jms20subset.JMSException
+}
+
+// newAsyncSendTracker returns a tracker for the asynchronous sends made using
+// the specified connection handle.
+func newAsyncSendTracker(qMgr ibmmq.MQQueueManager) *asyncSendTracker {
+	return &asyncSendTracker{qMgr: qMgr}
+}
+
+// send calls the put function to send a message asynchronously to the
+// specified queue, and records the message so that its outcome is reported to
+// the listener later. An error from the put function is returned to the caller
+// instead.
+func (tracker *asyncSendTracker) send(msg jms20subset.Message, queue QueueImpl,
+	listener jms20subset.CompletionListener, put func() error) error {
+
+	tracker.mutex.Lock()
+
+	err := put()
+	if err != nil {
+		tracker.mutex.Unlock()
+		return err
+	}
+
+	tracker.pending = append(tracker.pending, asyncSend{msg: msg, queue: queue, listener: listener})
+
+	checkNow := len(tracker.pending) >= asyncSendCheckCount
+	if !checkNow && tracker.timer == nil {
+		tracker.timer = time.AfterFunc(asyncSendCheckInterval, tracker.check)
+	}
+
+	tracker.mutex.Unlock()
+
+	if checkNow {
+		tracker.check()
+	}
+
+	return nil
+}
+
+// check asks the queue manager for the outcome of the pending asynchronous
+// sends, and reports it to their listeners.
+func (tracker *asyncSendTracker) check() {
+
+	tracker.mutex.Lock()
+
+	if tracker.timer != nil {
+		tracker.timer.Stop()
+		tracker.timer = nil
+	}
+
+	pending := tracker.pending
+	tracker.pending = nil
+
+	if len(pending) > 0 && !tracker.closed {
+		sts := ibmmq.NewMQSTS()
+		err := tracker.qMgr.Stat(ibmmq.MQSTAT_TYPE_ASYNC_ERROR, sts)
+
+		if err != nil {
+			failure := createMQException(err)
+			for _, send := range pending {
+				tracker.reports = append(tracker.reports, asyncReport{send: send, failure: failure})
+			}
+		} else {
+			tracker.reports = append(tracker.reports, outcomes(pending, sts)...)
+		}
+	}
+
+	// If another goroutine is already reporting outcomes (including a listener
+	// on this goroutine that has sent another message) then it reports these
+	// ones as well once it has finished with the earlier ones.
+	if tracker.reporting {
+		tracker.mutex.Unlock()
+		return
+	}
+	tracker.reporting = true
+
+	for len(tracker.reports) > 0 {
+		report := tracker.reports[0]
+		tracker.reports = tracker.reports[1:]
+		tracker.mutex.Unlock()
+
+		if report.failure != nil {
+			report.send.listener.OnException(report.send.msg, report.failure)
+		} else {
+			report.send.listener.OnCompletion(report.send.msg)
+		}
+
+		tracker.mutex.Lock()
+	}
+
+	tracker.reporting = false
+	tracker.mutex.Unlock()
+}
+
+// close stops the timer before the connection handle is disconnected. The
+// outcome of any sends has already been checked by closing the contexts.
+func (tracker *asyncSendTracker) close() {
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.timer != nil {
+		tracker.timer.Stop()
+		tracker.timer = nil
+	}
+	tracker.closed = true
+}
+
+// outcomes works out which of the pending sends failed from the result of
+// MQSTAT. Every send failed if the failure count covers all of them, and if
+// it matches the number of sends to the queue of the first failure then those
+// are the ones that failed. Otherwise the queue manager has not said which of
+// the sends failed, so all of them are reported as failed with an error that
+// gives the number of failures.
+func outcomes(pending []asyncSend, sts *ibmmq.MQSTS) []asyncReport {
+
+	failureCount := int(sts.PutFailureCount)
+	reports := make([]asyncReport, len(pending))
+	for i, send := range pending {
+		reports[i].send = send
+	}
+
+	if failureCount == 0 {
+		return reports
+	}
+
+	rcInt := int(sts.Reason)
+	reason := ibmmq.MQItoString("RC", rcInt)
+	failure := jms20subset.CreateJMSException(reason, strconv.Itoa(rcInt), nil)
+
+	objectName := strings.TrimSpace(sts.ObjectName)
+	objectQMgrName := strings.TrimSpace(sts.ObjectQMgrName)
+	failedQueue := func(queue QueueImpl) bool {
+		return queue.queueName == objectName &&
+			(queue.queueManagerName == "" || queue.queueManagerName == objectQMgrName)
+	}
+
+	matching := 0
+	for _, send := range pending {
+		if failedQueue(send.queue) {
+			matching++
+		}
+	}
+
+	switch {
+	case failureCount >= len(pending):
+		for i := range reports {
+			reports[i].failure = failure
+		}
+
+	case matching == failureCount:
+		for i := range reports {
+			if failedQueue(reports[i].send.queue) {
+				reports[i].failure = failure
+			}
+		}
+
+	default:
+		unknown := jms20subset.CreateJMSException(strconv.Itoa(failureCount)+" of "+
+			strconv.Itoa(len(pending))+" asynchronous sends failed, the first to "+objectName+
+			" with "+reason, strconv.Itoa(rcInt), nil)
+		for i := range reports {
+			reports[i].failure = unknown
+		}
+	}
+
+	return reports
+}
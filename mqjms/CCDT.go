@@ -0,0 +1,219 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CCDT represents the contents of a client channel definition table (CCDT)
+// in the JSON format, as described in the IBM MQ documentation;
+// https://www.ibm.com/support/knowledgecenter/en/SSFKSJ_latest/com.ibm.mq.con.doc/q132905_.htm
+//
+// Only the attributes that are needed to identify and validate the channels
+// are represented here. The file can still contain any other attributes, which
+// are applied by the MQ client when the CCDT is used to make a connection.
+type CCDT struct {
+	Channels []CCDTChannel `json:"channel"`
+}
+
+// CCDTChannel represents the definition of a single channel in a JSON CCDT.
+type CCDTChannel struct {
+	Name                 string                   `json:"name"`
+	Type                 string                   `json:"type"`
+	ClientConnection     CCDTClientConnection     `json:"clientConnection"`
+	TransmissionSecurity CCDTTransmissionSecurity `json:"transmissionSecurity"`
+}
+
+// CCDTClientConnection contains the details of the queue manager that a
+// client channel in a JSON CCDT connects to.
+type CCDTClientConnection struct {
+	Connection   []CCDTConnection `json:"connection"`
+	QueueManager string           `json:"queueManager"`
+}
+
+// CCDTConnection contains a single host and port to which a client channel in
+// a JSON CCDT can connect.
+type CCDTConnection struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// CCDTTransmissionSecurity contains the TLS settings of a client channel in a
+// JSON CCDT.
+type CCDTTransmissionSecurity struct {
+	CipherSpecification string `json:"cipherSpecification"`
+	CertificateLabel    string `json:"certificateLabel"`
+	CertificatePeerName string `json:"certificatePeerName"`
+}
+
+// The type of channel that is used by client applications in a JSON CCDT.
+const ccdtChannelType_CLIENT = "clientConnection"
+
+// The maximum length of an MQ channel name.
+const maxChannelNameLength = 20
+
+// ParseCCDT reads a JSON format CCDT from the specified file so that it can be
+// validated, or the channels that it contains can be listed, before using it
+// to connect to a queue manager.
+//
+// Note that the binary (AMQCLCHL.TAB) format of CCDT cannot be read by this
+// function, but can still be used to connect to a queue manager by setting the
+// CCDTURL property of a ConnectionFactory.
+func ParseCCDT(fileName string) (*CCDT, error) {
+
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCCDTFromBytes(content, fileName)
+}
+
+// ParseCCDTFromBytes parses the content of a JSON format CCDT, for example
+// one that has been downloaded from an HTTP server. The name is used to
+// identify the CCDT in any error message.
+func ParseCCDTFromBytes(content []byte, name string) (*CCDT, error) {
+
+	trimmed := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, errors.New("The CCDT " + name + " is not in the JSON format. Binary CCDTs can" +
+			" be used with the CCDTURL property of a ConnectionFactory, but cannot be parsed.")
+	}
+
+	var ccdt CCDT
+	err := json.Unmarshal(content, &ccdt)
+	if err != nil {
+		return nil, errors.New("Failure during unmarshalling CCDT from JSON: " + name + ": " + err.Error())
+	}
+
+	return &ccdt, nil
+}
+
+// Validate checks the channel definitions in the CCDT and returns a list of
+// all of the problems that it finds, or an empty list if the CCDT is valid.
+func (ccdt *CCDT) Validate() []error {
+
+	var errs []error
+
+	if len(ccdt.Channels) == 0 {
+		errs = append(errs, errors.New("The CCDT does not contain any channels"))
+	}
+
+	for i, channel := range ccdt.Channels {
+
+		// Identify the channel by name if it has one, or by position otherwise.
+		channelID := "channel " + strconv.Itoa(i)
+		if channel.Name != "" {
+			channelID = channelID + " (" + channel.Name + ")"
+		}
+
+		if channel.Name == "" {
+			errs = append(errs, errors.New(channelID+" does not have a name"))
+		} else if len(channel.Name) > maxChannelNameLength {
+			errs = append(errs, errors.New(channelID+" has a name longer than "+
+				strconv.Itoa(maxChannelNameLength)+" characters"))
+		}
+
+		if channel.Type != ccdtChannelType_CLIENT {
+			errs = append(errs, errors.New(channelID+" has type \""+channel.Type+
+				"\" but only \""+ccdtChannelType_CLIENT+"\" channels can be used by client applications"))
+			continue
+		}
+
+		if len(channel.ClientConnection.Connection) == 0 {
+			errs = append(errs, errors.New(channelID+" does not specify any connections"))
+		}
+
+		for _, conn := range channel.ClientConnection.Connection {
+
+			if conn.Host == "" {
+				errs = append(errs, errors.New(channelID+" has a connection with no host"))
+			}
+
+			// A port of zero means that the default MQ port (1414) is used.
+			if conn.Port < 0 || conn.Port > 65535 {
+				errs = append(errs, errors.New(channelID+" has a connection with an invalid port "+
+					strconv.Itoa(conn.Port)))
+			}
+		}
+	}
+
+	return errs
+}
+
+// ChannelNames returns the names of the channels defined in the CCDT, in the
+// order that they appear in the file.
+func (ccdt *CCDT) ChannelNames() []string {
+
+	names := make([]string, len(ccdt.Channels))
+	for i, channel := range ccdt.Channels {
+		names[i] = channel.Name
+	}
+
+	return names
+}
+
+// ChannelsForQueueManager returns the client channels that can be used to
+// connect to the specified queue manager name, which can also use the "*GROUP"
+// syntax to select the channels for a queue manager group. An empty name
+// matches every client channel, in the same way as the MQ client.
+func (ccdt *CCDT) ChannelsForQueueManager(qmName string) []CCDTChannel {
+
+	qmName = strings.TrimPrefix(qmName, "*")
+
+	var channels []CCDTChannel
+	for _, channel := range ccdt.Channels {
+		if channel.Type == ccdtChannelType_CLIENT &&
+			(qmName == "" || channel.ClientConnection.QueueManager == qmName) {
+			channels = append(channels, channel)
+		}
+	}
+
+	return channels
+}
+
+// GetConnectionNameList returns the connections of this channel in the MQ
+// connection name format, for example "host1(1414),host2(1414)".
+func (channel CCDTChannel) GetConnectionNameList() string {
+
+	connNames := make([]string, len(channel.ClientConnection.Connection))
+	for i, conn := range channel.ClientConnection.Connection {
+		port := conn.Port
+		if port == 0 {
+			port = 1414
+		}
+		connNames[i] = conn.Host + "(" + strconv.Itoa(port) + ")"
+	}
+
+	return strings.Join(connNames, ",")
+}
+
+// getCCDTURL converts the CCDTURL property of a ConnectionFactory into the URL
+// format expected by the MQ client, so that applications can specify either a
+// URL or a path to a file on the local file system.
+func getCCDTURL(ccdtLocation string) string {
+
+	if strings.Contains(ccdtLocation, "://") {
+		return ccdtLocation
+	}
+
+	absPath, err := filepath.Abs(ccdtLocation)
+	if err != nil {
+		absPath = ccdtLocation
+	}
+
+	return "file://" + filepath.ToSlash(absPath)
+}
@@ -0,0 +1,145 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang/ibmmq"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// checkReachable checks that a TCP connection can be made to at least one of
+// the hosts in an MQ connection name list before the deadline, so that an
+// incorrect hostname or port is reported quickly rather than waiting for the
+// MQ client to give up. If none of the hosts can be reached then the error
+// describes the failure for each of them, and has the error code of the
+// failure for the last host that was tried.
+func checkReachable(connNameList string, deadline time.Time) jms20subset.JMSException {
+
+	var failures []string
+	var errCode string
+	var lastErr error
+
+	for _, connName := range strings.Split(connNameList, ",") {
+
+		hostname, port, err := parseConnectionName(strings.TrimSpace(connName))
+		if err != nil {
+			// Leave the MQ client to report connection names that it doesn't like.
+			return nil
+		}
+
+		address := net.JoinHostPort(hostname, strconv.Itoa(port))
+		conn, err := net.DialTimeout("tcp", address, time.Until(deadline))
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		var reason string
+		errCode, reason = classifyDialError(err)
+		failures = append(failures, address+" "+reason)
+		lastErr = err
+
+		if !time.Now().Before(deadline) {
+			break
+		}
+	}
+
+	return jms20subset.CreateJMSException("Unable to reach the queue manager: "+strings.Join(failures, "; "),
+		errCode, lastErr)
+}
+
+// classifyDialError works out why a TCP connection could not be made, so that
+// applications can tell the difference between a hostname that does not
+// exist, a port that nothing is listening on and a host that does not respond.
+func classifyDialError(err error) (errCode string, reason string) {
+
+	if dnsErr, ok := err.(*net.DNSError); ok {
+		return ErrorCode_UNKNOWN_HOST, "could not be resolved (" + dnsErr.Err + ")"
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return ErrorCode_CONNECT_TIMEOUT, "did not respond before the timeout expired"
+	}
+
+	if opErr, ok := err.(*net.OpError); ok {
+
+		// Name resolution failures are wrapped in the OpError by the dialer.
+		if dnsErr, ok := opErr.Err.(*net.DNSError); ok {
+			return ErrorCode_UNKNOWN_HOST, "could not be resolved (" + dnsErr.Err + ")"
+		}
+
+		sysErr := opErr.Err
+		if osErr, ok := sysErr.(*os.SyscallError); ok {
+			sysErr = osErr.Err
+		}
+
+		if sysErr == syscall.ECONNREFUSED {
+			return ErrorCode_CONNECTION_REFUSED, "refused the connection"
+		}
+	}
+
+	return ErrorCode_HOST_UNREACHABLE, "could not be reached (" + err.Error() + ")"
+}
+
+// connxResult holds the outcome of a call to ibmmq.Connx.
+type connxResult struct {
+	qMgr ibmmq.MQQueueManager
+	err  error
+}
+
+// connxWithDeadline connects to the queue manager, returning timedOut=true if
+// the connection is not established before the deadline. A zero deadline
+// means that there is no time limit.
+//
+// The MQ client call cannot be interrupted, so if the deadline passes it is
+// left to complete in the background and any connection that it eventually
+// makes is disconnected again.
+func connxWithDeadline(qMgrName string, cno *ibmmq.MQCNO, deadline time.Time) (qMgr ibmmq.MQQueueManager, timedOut bool, err error) {
+
+	if deadline.IsZero() {
+		qMgr, err = ibmmq.Connx(qMgrName, cno)
+		return qMgr, false, err
+	}
+
+	results := make(chan connxResult, 1)
+	go func() {
+		connQMgr, connErr := ibmmq.Connx(qMgrName, cno)
+		results <- connxResult{connQMgr, connErr}
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case result := <-results:
+		return result.qMgr, false, result.err
+
+	case <-timer.C:
+		go func() {
+			result := <-results
+			if result.err == nil {
+				result.qMgr.Disc()
+			}
+		}()
+		return ibmmq.MQQueueManager{}, true, nil
+	}
+}
+
+// createConnectTimeoutException returns the error that is given to an
+// application if a connection cannot be established within the ConnectTimeout.
+func createConnectTimeoutException(reason string) jms20subset.JMSException {
+	return jms20subset.CreateJMSException(reason, ErrorCode_CONNECT_TIMEOUT, nil)
+}
@@ -0,0 +1,270 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// connectionFactoryConfig is the schema of a single profile in a connection
+// factory configuration file, as described on LoadConnectionFactory.
+type connectionFactoryConfig struct {
+	QueueManagerName       string `json:"queueManagerName" yaml:"queueManagerName"`
+	Hostname               string `json:"hostname" yaml:"hostname"`
+	ListenerPort           int    `json:"listenerPort" yaml:"listenerPort"`
+	ConnectionNameList     string `json:"connectionNameList" yaml:"connectionNameList"`
+	ApplicationChannelName string `json:"applicationChannelName" yaml:"applicationChannelName"`
+	CCDTURL                string `json:"ccdtUrl" yaml:"ccdtUrl"`
+	TransportType          string `json:"transportType" yaml:"transportType"`
+	Username               string `json:"username" yaml:"username"`
+	Password               string `json:"password" yaml:"password"`
+	TLSCipherSpec          string `json:"tlsCipherSpec" yaml:"tlsCipherSpec"`
+	TLSClientAuth          string `json:"tlsClientAuth" yaml:"tlsClientAuth"`
+	KeyRepository          string `json:"keyRepository" yaml:"keyRepository"`
+	CertificateLabel       string `json:"certificateLabel" yaml:"certificateLabel"`
+	TLSPeerName            string `json:"tlsPeerName" yaml:"tlsPeerName"`
+	TLSKeyResetCount       int    `json:"tlsKeyResetCount" yaml:"tlsKeyResetCount"`
+	TLSFipsRequired        bool   `json:"tlsFipsRequired" yaml:"tlsFipsRequired"`
+	ClientReconnect        string `json:"clientReconnect" yaml:"clientReconnect"`
+	ClientReconnectTimeout int    `json:"clientReconnectTimeout" yaml:"clientReconnectTimeout"`
+	ConnectTimeout         int    `json:"connectTimeout" yaml:"connectTimeout"`
+	ApplicationName        string `json:"applicationName" yaml:"applicationName"`
+	MaxMsgLength           int    `json:"maxMsgLength" yaml:"maxMsgLength"`
+	KeepAliveInterval      int    `json:"keepAliveInterval" yaml:"keepAliveInterval"`
+	SharingConversations   int    `json:"sharingConversations" yaml:"sharingConversations"`
+	HandleCacheSize        int    `json:"handleCacheSize" yaml:"handleCacheSize"`
+	DeliveryDelayQueue     string `json:"deliveryDelayQueue" yaml:"deliveryDelayQueue"`
+}
+
+// connectionFactoryConfigFile is the schema of the top level of a connection
+// factory configuration file, which contains one or more named profiles.
+type connectionFactoryConfigFile struct {
+	Profiles map[string]*connectionFactoryConfig `json:"profiles" yaml:"profiles"`
+}
+
+// LoadConnectionFactory is a utility method that creates a JMS
+// ConnectionFactory object that is populated with properties from a named
+// profile in a configuration file, so that the settings for several
+// environments can be kept together in a single file.
+//
+// The file can be in either the JSON or the YAML format, as indicated by its
+// extension (.json, .yaml or .yml). The profile can be left empty if the file
+// only contains one profile. For example;
+//
+//	profiles:
+//	  prod-eu:
+//	    queueManagerName: QM1
+//	    connectionNameList: "eu-host1(1414),eu-host2(1414)"
+//	    applicationChannelName: APP.SVRCONN
+//	    username: app
+//	    password: passw0rd
+//	    tlsCipherSpec: ANY_TLS12
+//	    keyRepository: /var/mqm/ssl/key
+//	    clientReconnect: qmgr
+//	  local:
+//	    queueManagerName: QM1
+//	    transportType: bindings
+//
+// Each profile can contain the following attributes;
+//   - queueManagerName         the queue manager name
+//   - hostname, listenerPort   the host and port of the queue manager listener (the port defaults to 1414)
+//   - connectionNameList       a list of connection names, such as "host1(1414),host2(1414)"
+//   - applicationChannelName   the name of the server connection channel
+//   - ccdtUrl                  the URL or path of a client channel definition table
+//   - transportType            "client" (the default) or "bindings"
+//   - username, password       the credentials used to connect
+//   - tlsCipherSpec            the TLS CipherSpec, for example ANY_TLS12
+//   - tlsClientAuth            "NONE" or "REQUIRED"
+//   - keyRepository            the path to the key repository, without the file extension
+//   - certificateLabel         the label of the client certificate
+//   - tlsPeerName              the distinguished name that the queue manager certificate must match
+//   - tlsKeyResetCount         the number of bytes after which the TLS secret key is renegotiated
+//   - tlsFipsRequired          true to only use FIPS-certified cryptography
+//   - clientReconnect          "asdef", "disabled", "reconnect" or "qmgr"
+//   - clientReconnectTimeout   the client reconnect timeout in seconds
+//   - connectTimeout           the connect timeout in milliseconds
+//   - applicationName          the name that identifies the application to the queue manager
+//   - maxMsgLength, keepAliveInterval, sharingConversations
+//     the channel attributes, as described on ConnectionFactoryImpl
+//   - handleCacheSize          the number of queues that each context keeps open for sending
+//   - deliveryDelayQueue       the staging queue for messages sent with a delivery delay
+//
+// The file is checked against this schema before the ConnectionFactory is
+// created, and an error is returned that describes every problem found in the
+// selected profile, including any attributes that are not recognised.
+func LoadConnectionFactory(fileName string, profile string) (cf ConnectionFactoryImpl, err error) {
+
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return ConnectionFactoryImpl{}, err
+	}
+
+	var configFile connectionFactoryConfigFile
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&configFile)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(content, &configFile)
+	default:
+		return ConnectionFactoryImpl{}, errors.New("Unable to determine the format of " + fileName +
+			", expected a file name ending in .json, .yaml or .yml")
+	}
+
+	if err != nil {
+		return ConnectionFactoryImpl{}, errors.New("Failure during parsing configuration file " + fileName + ": " + err.Error())
+	}
+
+	profileNames := make([]string, 0, len(configFile.Profiles))
+	for name := range configFile.Profiles {
+		profileNames = append(profileNames, name)
+	}
+	sort.Strings(profileNames)
+
+	if len(profileNames) == 0 {
+		return ConnectionFactoryImpl{}, errors.New("No profiles are defined in " + fileName)
+	}
+
+	if profile == "" {
+		if len(profileNames) > 1 {
+			return ConnectionFactoryImpl{}, errors.New("A profile must be specified for " + fileName +
+				", which contains the profiles " + strings.Join(profileNames, ", "))
+		}
+		profile = profileNames[0]
+	}
+
+	config := configFile.Profiles[profile]
+	if config == nil {
+		return ConnectionFactoryImpl{}, errors.New("Unable to find profile " + profile + " in " + fileName +
+			", which contains the profiles " + strings.Join(profileNames, ", "))
+	}
+
+	cf, problems := config.toConnectionFactory()
+	if len(problems) > 0 {
+		return ConnectionFactoryImpl{}, errors.New("Invalid profile " + profile + " in " + fileName + ": " +
+			strings.Join(problems, "; "))
+	}
+
+	return cf, nil
+}
+
+// toConnectionFactory checks the values in a configuration profile and
+// converts them into a ConnectionFactory, returning a description of every
+// problem that is found.
+func (config *connectionFactoryConfig) toConnectionFactory() (ConnectionFactoryImpl, []string) {
+
+	var problems []string
+
+	cf := ConnectionFactoryImpl{
+		QMName:               config.QueueManagerName,
+		Hostname:             config.Hostname,
+		PortNumber:           config.ListenerPort,
+		ConnectionNameList:   config.ConnectionNameList,
+		ChannelName:          config.ApplicationChannelName,
+		CCDTURL:              config.CCDTURL,
+		UserName:             config.Username,
+		Password:             config.Password,
+		TLSCipherSpec:        config.TLSCipherSpec,
+		TLSClientAuth:        config.TLSClientAuth,
+		KeyRepository:        config.KeyRepository,
+		CertificateLabel:     config.CertificateLabel,
+		TLSPeerName:          config.TLSPeerName,
+		TLSKeyResetCount:     config.TLSKeyResetCount,
+		TLSFipsRequired:      config.TLSFipsRequired,
+		ApplicationName:      config.ApplicationName,
+		MaxMsgLength:         config.MaxMsgLength,
+		KeepAliveInterval:    config.KeepAliveInterval,
+		SharingConversations: config.SharingConversations,
+		HandleCacheSize:      config.HandleCacheSize,
+		DeliveryDelayQueue:   config.DeliveryDelayQueue,
+	}
+
+	if config.TransportType != "" {
+		var ok bool
+		cf.TransportType, ok = parseTransportTypeName(config.TransportType)
+		if !ok {
+			problems = append(problems, "transportType must be "+uriTransportClient+" or "+
+				uriTransportBindings+", not \""+config.TransportType+"\"")
+		}
+	}
+
+	if cf.TransportType == TransportType_BINDINGS {
+		for attr, value := range map[string]string{
+			"hostname":               config.Hostname,
+			"connectionNameList":     config.ConnectionNameList,
+			"applicationChannelName": config.ApplicationChannelName,
+			"ccdtUrl":                config.CCDTURL,
+		} {
+			if value != "" {
+				problems = append(problems, attr+" must not be specified when transportType is "+uriTransportBindings)
+			}
+		}
+	} else {
+		if config.Hostname == "" && config.ConnectionNameList == "" && config.CCDTURL == "" {
+			problems = append(problems, "one of hostname, connectionNameList or ccdtUrl must be specified")
+		}
+
+		if config.ApplicationChannelName == "" && config.CCDTURL == "" {
+			problems = append(problems, "applicationChannelName must be specified unless ccdtUrl is used")
+		}
+
+		if config.Hostname != "" && config.ListenerPort == 0 {
+			cf.PortNumber = 1414
+		}
+	}
+
+	if config.ListenerPort < 0 || config.ListenerPort > 65535 {
+		problems = append(problems, "listenerPort must be between 1 and 65535, not "+strconv.Itoa(config.ListenerPort))
+	}
+
+	if config.Password != "" && config.Username == "" {
+		problems = append(problems, "username must be specified when password is specified")
+	}
+
+	if config.TLSClientAuth != "" && config.TLSClientAuth != TLSClientAuth_NONE &&
+		config.TLSClientAuth != TLSClientAuth_REQUIRED {
+		problems = append(problems, "tlsClientAuth must be "+TLSClientAuth_NONE+" or "+
+			TLSClientAuth_REQUIRED+", not \""+config.TLSClientAuth+"\"")
+	}
+
+	if config.ClientReconnect != "" {
+		var ok bool
+		cf.ClientReconnectOptions, ok = parseReconnectOptionName(config.ClientReconnect)
+		if !ok {
+			problems = append(problems, "clientReconnect must be one of asdef, disabled, reconnect or qmgr, not \""+
+				config.ClientReconnect+"\"")
+		}
+	}
+
+	if config.ClientReconnectTimeout < 0 {
+		problems = append(problems, "clientReconnectTimeout must not be negative")
+	}
+	cf.ClientReconnectTimeout = config.ClientReconnectTimeout
+
+	if config.ConnectTimeout < 0 {
+		problems = append(problems, "connectTimeout must not be negative")
+	}
+	cf.ConnectTimeout = config.ConnectTimeout
+
+	// Report the problems in a consistent order.
+	sort.Strings(problems)
+
+	return cf, problems
+}
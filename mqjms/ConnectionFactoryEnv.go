@@ -0,0 +1,347 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The prefix of the environment variables that are read by
+// CreateConnectionFactoryFromEnv if the application does not specify one.
+const defaultEnvPrefix = "MQJMS"
+
+// The names of the environment variables read by CreateConnectionFactoryFromEnv,
+// which are appended to the prefix chosen by the application.
+const (
+	envQMName                 = "QMNAME"
+	envHostname               = "HOSTNAME"
+	envPort                   = "PORT"
+	envChannel                = "CHANNEL"
+	envUserName               = "USERNAME"
+	envPassword               = "PASSWORD"
+	envConnectionNameList     = "CONNECTION_NAME_LIST"
+	envCCDTURL                = "CCDT_URL"
+	envTransport              = "TRANSPORT"
+	envTLSCipherSpec          = "TLS_CIPHERSPEC"
+	envTLSClientAuth          = "TLS_CLIENTAUTH"
+	envKeyRepository          = "KEY_REPOSITORY"
+	envCertificateLabel       = "CERTIFICATE_LABEL"
+	envTLSPeerName            = "TLS_PEER_NAME"
+	envTLSKeyResetCount       = "TLS_KEY_RESET_COUNT"
+	envTLSFipsRequired        = "TLS_FIPS_REQUIRED"
+	envClientReconnect        = "CLIENT_RECONNECT"
+	envClientReconnectTimeout = "CLIENT_RECONNECT_TIMEOUT"
+	envConnectTimeout         = "CONNECT_TIMEOUT"
+	envApplicationName        = "APPLICATION_NAME"
+	envMaxMsgLength           = "MAX_MSG_LENGTH"
+	envKeepAliveInterval      = "KEEPALIVE_INTERVAL"
+	envSharingConversations   = "SHARING_CONVERSATIONS"
+	envHandleCacheSize        = "HANDLE_CACHE_SIZE"
+	envDeliveryDelayQueue     = "DELIVERY_DELAY_QUEUE"
+)
+
+// The standard environment variables that are used by the MQ client.
+const (
+	envMQSERVER  = "MQSERVER"
+	envMQCHLLIB  = "MQCHLLIB"
+	envMQCHLTAB  = "MQCHLTAB"
+	envMQCCDTURL = "MQCCDTURL"
+	envMQSSLKEYR = "MQSSLKEYR"
+)
+
+// The default file name of a binary CCDT, used if MQCHLLIB is set without MQCHLTAB.
+const defaultCCDTFileName = "AMQCLCHL.TAB"
+
+// CreateConnectionFactoryFromEnv is a utility method that creates a JMS
+// ConnectionFactory object that is populated with properties from environment
+// variables, which is convenient for applications that are deployed in
+// containers.
+//
+// The following variables are read, where PREFIX is the prefix specified by
+// the application, or MQJMS if the prefix is empty;
+//   - PREFIX_QMNAME                    the queue manager name
+//   - PREFIX_HOSTNAME, PREFIX_PORT     the host and port of the queue manager listener
+//   - PREFIX_CONNECTION_NAME_LIST      a list of connection names, such as "host1(1414),host2(1414)"
+//   - PREFIX_CHANNEL                   the name of the server connection channel
+//   - PREFIX_USERNAME, PREFIX_PASSWORD the credentials used to connect
+//   - PREFIX_CCDT_URL                  the URL or path of a client channel definition table
+//   - PREFIX_TRANSPORT                 "client" (the default) or "bindings"
+//   - PREFIX_TLS_CIPHERSPEC            the TLS CipherSpec, for example ANY_TLS12
+//   - PREFIX_TLS_CLIENTAUTH            "NONE" or "REQUIRED"
+//   - PREFIX_KEY_REPOSITORY            the path to the key repository, without the file extension
+//   - PREFIX_CERTIFICATE_LABEL         the label of the client certificate
+//   - PREFIX_TLS_PEER_NAME             the distinguished name that the queue manager certificate must match
+//   - PREFIX_TLS_KEY_RESET_COUNT       the number of bytes after which the TLS secret key is renegotiated
+//   - PREFIX_TLS_FIPS_REQUIRED         "true" to only use FIPS-certified cryptography
+//   - PREFIX_CLIENT_RECONNECT          "asdef", "disabled", "reconnect" or "qmgr"
+//   - PREFIX_CLIENT_RECONNECT_TIMEOUT  the client reconnect timeout in seconds
+//   - PREFIX_CONNECT_TIMEOUT           the connect timeout in milliseconds
+//   - PREFIX_APPLICATION_NAME          the name that identifies the application to the queue manager
+//   - PREFIX_MAX_MSG_LENGTH, PREFIX_KEEPALIVE_INTERVAL and PREFIX_SHARING_CONVERSATIONS
+//     the channel attributes, as described on ConnectionFactoryImpl
+//   - PREFIX_HANDLE_CACHE_SIZE         the number of queues that each context keeps open for sending
+//   - PREFIX_DELIVERY_DELAY_QUEUE      the staging queue for messages sent with a delivery delay
+//
+// The standard MQ client variables MQSERVER, MQCCDTURL, MQCHLLIB/MQCHLTAB and
+// MQSSLKEYR are also honoured, with the same meaning as for any other MQ client
+// application. If a prefixed variable sets the same property as one of the
+// standard variables then the prefixed variable takes precedence, and replaces
+// any other properties that the standard variables set for the same purpose.
+// For example PREFIX_CONNECTION_NAME_LIST replaces the host from MQSERVER, and
+// PREFIX_HOSTNAME or PREFIX_CHANNEL replaces a CCDT from MQCHLLIB/MQCHLTAB or
+// MQCCDTURL.
+func CreateConnectionFactoryFromEnv(prefix string) (cf ConnectionFactoryImpl, err error) {
+
+	if prefix == "" {
+		prefix = defaultEnvPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, "_") + "_"
+
+	// Start with the standard MQ variables, so that they can be overridden.
+	err = applyStandardMQEnv(&cf)
+	if err != nil {
+		return ConnectionFactoryImpl{}, err
+	}
+
+	// The standard variables either name a host (or list of hosts) and a
+	// channel, or a CCDT, so a prefixed variable for one of these replaces
+	// whichever of the others would conflict with it.
+	isSet := func(name string) bool {
+		return os.Getenv(prefix+name) != ""
+	}
+
+	if isSet(envHostname) || isSet(envConnectionNameList) {
+		cf.Hostname = ""
+		cf.PortNumber = 0
+		cf.ConnectionNameList = ""
+		cf.CCDTURL = ""
+	}
+
+	if isSet(envChannel) {
+		cf.CCDTURL = ""
+	}
+
+	if isSet(envCCDTURL) {
+		cf.Hostname = ""
+		cf.PortNumber = 0
+		cf.ConnectionNameList = ""
+		cf.ChannelName = ""
+	}
+
+	stringProps := []struct {
+		name  string
+		value *string
+	}{
+		{envQMName, &cf.QMName},
+		{envHostname, &cf.Hostname},
+		{envChannel, &cf.ChannelName},
+		{envUserName, &cf.UserName},
+		{envPassword, &cf.Password},
+		{envConnectionNameList, &cf.ConnectionNameList},
+		{envCCDTURL, &cf.CCDTURL},
+		{envTLSCipherSpec, &cf.TLSCipherSpec},
+		{envKeyRepository, &cf.KeyRepository},
+		{envCertificateLabel, &cf.CertificateLabel},
+		{envTLSPeerName, &cf.TLSPeerName},
+		{envApplicationName, &cf.ApplicationName},
+		{envDeliveryDelayQueue, &cf.DeliveryDelayQueue},
+	}
+
+	for _, prop := range stringProps {
+		if value, ok := os.LookupEnv(prefix + prop.name); ok {
+			*prop.value = value
+		}
+	}
+
+	if value, ok := os.LookupEnv(prefix + envPort); ok {
+		cf.PortNumber, err = strconv.Atoi(value)
+		if err != nil || cf.PortNumber < 1 || cf.PortNumber > 65535 {
+			return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+				prefix + envPort + ", expected a port number between 1 and 65535")
+		}
+	}
+
+	if value, ok := os.LookupEnv(prefix + envTransport); ok {
+		transportType, found := parseTransportTypeName(value)
+		if !found {
+			return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+				prefix + envTransport + ", expected " + uriTransportClient + " or " + uriTransportBindings)
+		}
+		cf.TransportType = transportType
+	}
+
+	if value, ok := os.LookupEnv(prefix + envTLSClientAuth); ok {
+		if value != TLSClientAuth_NONE && value != TLSClientAuth_REQUIRED {
+			return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+				prefix + envTLSClientAuth + ", expected " + TLSClientAuth_NONE + " or " + TLSClientAuth_REQUIRED)
+		}
+		cf.TLSClientAuth = value
+	}
+
+	if value, ok := os.LookupEnv(prefix + envTLSKeyResetCount); ok {
+		cf.TLSKeyResetCount, err = strconv.Atoi(value)
+		if err != nil {
+			return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+				prefix + envTLSKeyResetCount + ", expected a number of bytes")
+		}
+	}
+
+	if value, ok := os.LookupEnv(prefix + envTLSFipsRequired); ok {
+		cf.TLSFipsRequired, err = strconv.ParseBool(value)
+		if err != nil {
+			return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+				prefix + envTLSFipsRequired + ", expected true or false")
+		}
+	}
+
+	if value, ok := os.LookupEnv(prefix + envClientReconnect); ok {
+		reconnectOptions, found := parseReconnectOptionName(value)
+		if !found {
+			return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+				prefix + envClientReconnect + ", expected one of asdef, disabled, reconnect or qmgr")
+		}
+		cf.ClientReconnectOptions = reconnectOptions
+	}
+
+	if value, ok := os.LookupEnv(prefix + envClientReconnectTimeout); ok {
+		cf.ClientReconnectTimeout, err = strconv.Atoi(value)
+		if err != nil || cf.ClientReconnectTimeout < 0 {
+			return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+				prefix + envClientReconnectTimeout + ", expected a non-negative number of seconds")
+		}
+	}
+
+	if value, ok := os.LookupEnv(prefix + envConnectTimeout); ok {
+		cf.ConnectTimeout, err = strconv.Atoi(value)
+		if err != nil || cf.ConnectTimeout < 0 {
+			return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+				prefix + envConnectTimeout + ", expected a non-negative number of milliseconds")
+		}
+	}
+
+	intProps := []struct {
+		name  string
+		value *int
+	}{
+		{envMaxMsgLength, &cf.MaxMsgLength},
+		{envKeepAliveInterval, &cf.KeepAliveInterval},
+		{envSharingConversations, &cf.SharingConversations},
+		{envHandleCacheSize, &cf.HandleCacheSize},
+	}
+
+	for _, prop := range intProps {
+		if value, ok := os.LookupEnv(prefix + prop.name); ok {
+			*prop.value, err = strconv.Atoi(value)
+			if err != nil {
+				return ConnectionFactoryImpl{}, errors.New("Invalid value \"" + value + "\" for environment variable " +
+					prefix + prop.name + ", expected a number")
+			}
+		}
+	}
+
+	return cf, nil
+
+}
+
+// applyStandardMQEnv populates the ConnectionFactory from the environment
+// variables that are used to configure any MQ client application.
+func applyStandardMQEnv(cf *ConnectionFactoryImpl) error {
+
+	if value := os.Getenv(envMQSSLKEYR); value != "" {
+		cf.KeyRepository = value
+	}
+
+	// MQSERVER defines a single channel in the format
+	// "CHANNEL/TCP/host(port)", and takes precedence over a CCDT in the same
+	// way as for the MQ client.
+	if value := os.Getenv(envMQSERVER); value != "" {
+		return applyMQSERVER(cf, value)
+	}
+
+	if value := os.Getenv(envMQCCDTURL); value != "" {
+		cf.CCDTURL = value
+		return nil
+	}
+
+	chlLib := os.Getenv(envMQCHLLIB)
+	chlTab := os.Getenv(envMQCHLTAB)
+
+	if chlLib != "" || chlTab != "" {
+		if chlTab == "" {
+			chlTab = defaultCCDTFileName
+		}
+		cf.CCDTURL = filepath.Join(chlLib, chlTab)
+	}
+
+	return nil
+}
+
+// applyMQSERVER populates the channel and connection name of the
+// ConnectionFactory from the value of the MQSERVER environment variable.
+func applyMQSERVER(cf *ConnectionFactoryImpl, value string) error {
+
+	parts := strings.SplitN(value, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return errors.New("Invalid value \"" + value + "\" for environment variable " + envMQSERVER +
+			", expected the format CHANNEL/TCP/host(port)")
+	}
+
+	if !strings.EqualFold(parts[1], "TCP") {
+		return errors.New("Unsupported transport \"" + parts[1] + "\" in environment variable " + envMQSERVER +
+			", only TCP is supported")
+	}
+
+	cf.ChannelName = parts[0]
+	connName := strings.TrimSpace(parts[2])
+
+	// A list of connection names is used as it is, while a single connection
+	// name is split into the hostname and port.
+	if strings.Contains(connName, ",") {
+		cf.ConnectionNameList = connName
+		return nil
+	}
+
+	hostname, port, err := parseConnectionName(connName)
+	if err != nil {
+		return errors.New("Invalid connection name in environment variable " + envMQSERVER + ": " + err.Error())
+	}
+
+	cf.Hostname = hostname
+	cf.PortNumber = port
+
+	return nil
+}
+
+// parseConnectionName splits a connection name in the MQ format "host(port)"
+// into the hostname and port, applying the default port of 1414 if the port
+// is not specified.
+func parseConnectionName(connName string) (string, int, error) {
+
+	idx := strings.Index(connName, "(")
+	if idx < 0 {
+		return connName, 1414, nil
+	}
+
+	if idx == 0 || !strings.HasSuffix(connName, ")") {
+		return "", 0, errors.New("\"" + connName + "\" is not in the format host(port)")
+	}
+
+	portStr := connName[idx+1 : len(connName)-1]
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, errors.New("Invalid port \"" + portStr + "\" in \"" + connName + "\"")
+	}
+
+	return connName[:idx], port, nil
+}
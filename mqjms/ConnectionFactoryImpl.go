@@ -10,9 +10,13 @@
 package mqjms
 
 import (
+	"context"
+	"errors"
 	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
 	"github.com/ibm-messaging/mq-golang/ibmmq"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // ConnectionFactoryImpl defines a struct that contains attributes for
@@ -29,6 +33,25 @@ type ConnectionFactoryImpl struct {
 	UserName    string
 	Password    string
 
+	// A comma separated list of connection names in the MQ format, for example
+	// "host1(1414),host2(1414)". If specified, this is used instead of Hostname
+	// and PortNumber so that the client can connect to whichever instance of a
+	// multi-instance or native HA queue manager is currently active. The
+	// connection names are tried in the order that they are listed.
+	ConnectionNameList string
+
+	// The location of a client channel definition table (CCDT) that defines
+	// the channel(s) to use for a client connection, in either the JSON or the
+	// binary (AMQCLCHL.TAB) format. This can be a URL such as
+	// "file:///var/mqm/ccdt.json" or "https://myserver/ccdt.json", or a path
+	// to a file on the local file system.
+	//
+	// If specified, the channel is selected from the CCDT by the queue manager
+	// name, and the Hostname, PortNumber, ConnectionNameList and ChannelName
+	// are not used. The QMName can also use the "*GROUP" syntax to connect to
+	// any queue manager in the queue manager group called GROUP in the CCDT.
+	CCDTURL string
+
 	TransportType int // Default to TransportType_CLIENT (0)
 
 	// Equivalent to SSLCipherSpec and SSLClientAuth in the MQI client, however
@@ -39,12 +62,151 @@ type ConnectionFactoryImpl struct {
 
 	KeyRepository    string
 	CertificateLabel string
+
+	// The distinguished name pattern that the certificate of the queue manager
+	// must match, for example "CN=QM1,O=IBM,C=GB", so that the client only
+	// connects to the intended queue manager. Equivalent to SSLPEER.
+	TLSPeerName string
+
+	// The number of bytes sent and received over a TLS connection before the
+	// secret key is renegotiated. The default of zero means that the key is
+	// never renegotiated, otherwise the value must be between 32768 and
+	// 999999999.
+	TLSKeyResetCount int
+
+	// Requires that only FIPS-certified cryptography is used for TLS
+	// connections, in which case only FIPS compliant CipherSpecs can be used.
+	TLSFipsRequired bool
+
+	// The name that identifies the application to the queue manager, which is
+	// shown as the APPLTAG in DISPLAY CONN output. If not specified then the
+	// MQ client uses the name of the program.
+	ApplicationName string
+
+	// The following properties of the client channel definition are sent to the
+	// queue manager when the channel starts, and the values that are used are
+	// negotiated with the server connection channel. A value of zero means that
+	// the MQ client default is used (shown in brackets).
+	MaxMsgLength         int // Maximum message length in bytes (4194304)
+	KeepAliveInterval    int // Seconds between TCP keepalives, or KeepAliveInterval_AUTO (AUTO)
+	SharingConversations int // Conversations per TCP socket, or SharingConversations_NONE (10)
+
+	// Controls whether a client connection is reconnected automatically if it
+	// is broken, for example by a queue manager restart. Producers and consumers
+	// continue to work after a successful reconnection without having to be
+	// recreated by the application.
+	ClientReconnectOptions int // Default to ClientReconnect_AS_DEF (0)
+
+	// The number of seconds after which the application stops waiting for an
+	// automatic reconnection to succeed, and is told that the connection has
+	// failed instead. From then on each JMS call made with the context fails
+	// straight away, but an MQ call that is already waiting for the
+	// reconnection to complete (such as a Receive) is not interrupted, and
+	// only returns once the MQ client itself gives up.
+	ClientReconnectTimeout int // Default to ClientReconnectTimeout_DEFAULT
+
+	// The maximum number of milliseconds that CreateContext waits for a
+	// connection to the queue manager to be established. For client connections
+	// each host is first checked to make sure that it is reachable, so that an
+	// incorrect hostname or port is reported straight away. The default of zero
+	// means that there is no timeout.
+	ConnectTimeout int
+
+	// The maximum number of queues that each context keeps open for sending
+	// messages, so that producers don't have to open and close the queue for
+	// every message that they send. When the limit is reached the queue that
+	// was least recently sent to is closed. Set to HandleCacheSize_DISABLED to
+	// open and close the queue for each message instead.
+	HandleCacheSize int // Default to HandleCacheSize_DEFAULT
+
+	// The name of the staging queue that holds messages sent with a delivery
+	// delay until they are due, when they are moved to their destination by a
+	// DelayScheduler. Messages can only be sent with a delivery delay if this
+	// is set.
+	DeliveryDelayQueue string
+
+	// Supplies the user name and password each time that a connection is made,
+	// instead of the UserName and Password properties, so that credentials
+	// can be rotated without having to recreate the ConnectionFactory.
+	// ClientReconnectOptions must be set to ClientReconnect_DISABLED when this
+	// is set, because the MQ client would reconnect using the original
+	// credentials.
+	CredentialsProvider CredentialsProvider
 }
 
 // CreateContext implements the JMS method to create a connection to an IBM MQ
 // queue manager.
 func (cf ConnectionFactoryImpl) CreateContext() (jms20subset.JMSContext, jms20subset.JMSException) {
 
+	var ctx jms20subset.JMSContext
+
+	// Report any problems with the configuration up front, rather than
+	// letting them surface one at a time as failures from the queue manager.
+	if errs := cf.Validate(); len(errs) > 0 {
+		return nil, createInvalidConfigException(errs)
+	}
+
+	qMgr, retErr := cf.connect()
+
+	if retErr == nil {
+
+		// Connection was created successfully, so we wrap the MQI object into
+		// a new ContextImpl and return it to the caller. The context owns the
+		// connection, which is shared with any further contexts that are
+		// created from it.
+		//
+		// If automatic reconnection might be enabled then the connection
+		// listens for reconnection events straight away, so that the reconnect
+		// timeout can be applied and the events passed on to any exception
+		// listener.
+		ctx = newContext(newConnection(cf, qMgr), jms20subset.JMSContext_AUTO_ACKNOWLEDGE)
+
+	}
+
+	return ctx, retErr
+
+}
+
+// connect uses the configuration parameters of this ConnectionFactory to
+// create a new connection handle to the IBM MQ queue manager.
+func (cf ConnectionFactoryImpl) connect() (ibmmq.MQQueueManager, jms20subset.JMSException) {
+
+	// If a timeout has been set then check that the queue manager can be
+	// reached before asking the MQ client to connect, as the client can take a
+	// long time to give up on a host that does not respond.
+	var deadline time.Time
+	if cf.ConnectTimeout > 0 {
+		deadline = time.Now().Add(time.Duration(cf.ConnectTimeout) * time.Millisecond)
+
+		if cf.TransportType == TransportType_CLIENT && cf.CCDTURL == "" {
+			reachErr := checkReachable(cf.getConnectionName(), deadline)
+			if reachErr != nil {
+				return ibmmq.MQQueueManager{}, reachErr
+			}
+		}
+	}
+
+	// Obtain the current credentials from the provider if there is one, so
+	// that each connection uses the latest version of a rotated secret.
+	userName := cf.UserName
+	password := cf.Password
+
+	if cf.CredentialsProvider != nil {
+		credsCtx := context.Background()
+		if !deadline.IsZero() {
+			var cancel context.CancelFunc
+			credsCtx, cancel = context.WithDeadline(credsCtx, deadline)
+			defer cancel()
+		}
+
+		var credsErr error
+		userName, password, credsErr = cf.CredentialsProvider.Credentials(credsCtx)
+		if credsErr != nil {
+			return ibmmq.MQQueueManager{}, jms20subset.CreateJMSException(
+				"Unable to obtain credentials: "+credsErr.Error(), ErrorCode_CREDENTIALS_UNAVAILABLE, credsErr)
+		}
+	}
+
 	// Allocate the internal structures required to create an connection to IBM MQ.
 	cno := ibmmq.NewMQCNO()
 
@@ -56,26 +218,75 @@ func (cf ConnectionFactoryImpl) CreateContext() (jms20subset.JMSContext, jms20su
 		// Fill in the required fields in the channel definition structure
 		cd := ibmmq.NewMQCD()
 		cd.ChannelName = cf.ChannelName
-		cd.ConnectionName = cf.Hostname + "(" + strconv.Itoa(cf.PortNumber) + ")"
-		cno.ClientConn = cd
+		cd.ConnectionName = cf.getConnectionName()
+
+		// Override the channel attributes that have been specified, leaving the
+		// others with the MQ client defaults.
+		if cf.MaxMsgLength != 0 {
+			cd.MaxMsgLength = int32(cf.MaxMsgLength)
+		}
+
+		if cf.KeepAliveInterval != 0 {
+			cd.KeepAliveInterval = int32(cf.KeepAliveInterval)
+		}
+
+		switch cf.SharingConversations {
+		case 0:
+		case SharingConversations_NONE:
+			cd.SharingConversations = 0
+		default:
+			cd.SharingConversations = int32(cf.SharingConversations)
+		}
+
+		if cf.CCDTURL != "" {
+			// The channel definition is read from the CCDT, which is only used by
+			// the MQ client if we don't also supply a channel definition.
+			cno.CCDTUrl = getCCDTURL(cf.CCDTURL)
+		} else {
+			cno.ClientConn = cd
+		}
+
+		// Apply the automatic client reconnection behaviour. The MQ client
+		// reconnects using the credentials of the original connection, so it
+		// is never allowed to reconnect when the credentials come from a
+		// provider. Validate requires ClientReconnect_DISABLED to be set in
+		// that case, so this only affects callers that skip validation.
+		reconnectOptions := cf.ClientReconnectOptions
+		if cf.CredentialsProvider != nil {
+			reconnectOptions = ClientReconnect_DISABLED
+		}
+
+		switch reconnectOptions {
+		case ClientReconnect_DISABLED:
+			cno.Options |= ibmmq.MQCNO_RECONNECT_DISABLED
+		case ClientReconnect_RECONNECT:
+			cno.Options |= ibmmq.MQCNO_RECONNECT
+		case ClientReconnect_QMGR:
+			cno.Options |= ibmmq.MQCNO_RECONNECT_Q_MGR
+		}
 
 		// Fill in the fields relating to TLS channel connections
 		if cf.TLSCipherSpec != "" {
 			cd.SSLCipherSpec = cf.TLSCipherSpec
 		}
 
+		if cf.TLSPeerName != "" {
+			cd.SSLPeerName = cf.TLSPeerName
+		}
+
 		switch cf.TLSClientAuth {
 		case TLSClientAuth_REQUIRED:
 			cd.SSLClientAuth = ibmmq.MQSCA_REQUIRED
 		case TLSClientAuth_NONE:
 		case "":
 			cd.SSLClientAuth = ibmmq.MQSCA_OPTIONAL
-		default:
-			cd.SSLClientAuth = -1 // Trigger an error message
 		}
 
-		// Set up the reference to the key repository file, if it has been specified.
-		if cf.KeyRepository != "" {
+		// Set up the reference to the key repository file, if it has been
+		// specified, along with any other TLS configuration options. If there
+		// is no key repository then the MQ client uses the MQSSLKEYR environment
+		// variable or the client configuration file instead.
+		if cf.KeyRepository != "" || cf.TLSKeyResetCount != 0 || cf.TLSFipsRequired {
 			sco := ibmmq.NewMQSCO()
 			sco.KeyRepository = cf.KeyRepository
 
@@ -83,6 +294,12 @@ func (cf ConnectionFactoryImpl) CreateContext() (jms20subset.JMSContext, jms20su
 				sco.CertificateLabel = cf.CertificateLabel
 			}
 
+			if cf.TLSKeyResetCount != 0 {
+				sco.KeyResetCount = int32(cf.TLSKeyResetCount)
+			}
+
+			sco.FipsRequired = cf.TLSFipsRequired
+
 			cno.SSLConfig = sco
 
 		}
@@ -94,34 +311,41 @@ func (cf ConnectionFactoryImpl) CreateContext() (jms20subset.JMSContext, jms20su
 
 	}
 
-	if cf.UserName != "" {
+	// Ask MQ to serialise calls that are made on the connection handle, so
+	// that a context (and the producers and consumers created from it) can be
+	// used from multiple goroutines. A call made while another goroutine is
+	// using the connection waits for that call to complete, rather than
+	// failing with MQRC_CALL_IN_PROGRESS.
+	cno.Options |= ibmmq.MQCNO_HANDLE_SHARE_BLOCK
+
+	if cf.ApplicationName != "" {
+		cno.ApplName = cf.ApplicationName
+	}
+
+	if userName != "" {
 
 		// Store the user credentials in an MQCSP, which ensures that long passwords
 		// can be used.
 		csp := ibmmq.NewMQCSP()
 		csp.AuthenticationType = ibmmq.MQCSP_AUTH_USER_ID_AND_PWD
-		csp.UserId = cf.UserName
-		csp.Password = cf.Password
+		csp.UserId = userName
+		csp.Password = password
 		cno.SecurityParms = csp
 
 	}
 
-	var ctx jms20subset.JMSContext
 	var retErr jms20subset.JMSException
 
 	// Use the objects that we have configured to create a connection to the
 	// queue manager.
-	qMgr, err := ibmmq.Connx(cf.QMName, cno)
+	qMgr, timedOut, err := connxWithDeadline(cf.QMName, cno, deadline)
 
-	if err == nil {
+	if timedOut {
 
-		// Connection was created successfully, so we wrap the MQI object into
-		// a new ContextImpl and return it to the caller.
-		ctx = ContextImpl{
-			qMgr: qMgr,
-		}
+		retErr = createConnectTimeoutException("Timed out after " + strconv.Itoa(cf.ConnectTimeout) +
+			"ms waiting for a connection to queue manager " + cf.QMName)
 
-	} else {
+	} else if err != nil {
 
 		// The underlying MQI call returned an error, so extract the relevant
 		// details and pass it back to the caller as a JMSException
@@ -132,6 +356,57 @@ func (cf ConnectionFactoryImpl) CreateContext() (jms20subset.JMSContext, jms20su
 
 	}
 
-	return ctx, retErr
+	return qMgr, retErr
+
+}
+
+// isReconnectEnabled returns true if client connections made by this
+// ConnectionFactory might be reconnected automatically. With the default of
+// ClientReconnect_AS_DEF this is decided by the channel definition or the
+// mqclient.ini file, which we cannot see, so it is assumed to be possible.
+// Reconnection is always disabled when a CredentialsProvider is used.
+func (cf ConnectionFactoryImpl) isReconnectEnabled() bool {
+	return cf.TransportType == TransportType_CLIENT &&
+		cf.ClientReconnectOptions != ClientReconnect_DISABLED &&
+		cf.CredentialsProvider == nil
+}
+
+// getHandleCacheSize returns the number of queue handles that each context
+// should keep open for sending messages.
+func (cf ConnectionFactoryImpl) getHandleCacheSize() int {
+	if cf.HandleCacheSize == 0 {
+		return HandleCacheSize_DEFAULT
+	}
+	return cf.HandleCacheSize
+}
+
+// getConnectionName returns the MQ connection name that should be used for a
+// client connection, which is either the connection name list if one has been
+// specified, or else the combination of the hostname and port number.
+func (cf ConnectionFactoryImpl) getConnectionName() string {
+
+	if cf.ConnectionNameList != "" {
+		return cf.ConnectionNameList
+	}
+
+	return cf.Hostname + "(" + strconv.Itoa(cf.PortNumber) + ")"
+}
+
+// CreateConnectionNameList is a helper function that builds a connection name
+// list in the MQ format (for example "host1(1414),host2(1414)") from an ordered
+// list of hostnames and the corresponding port numbers, so that it can be used
+// to populate the ConnectionNameList property of a ConnectionFactory.
+func CreateConnectionNameList(hostnames []string, ports []int) (string, error) {
+
+	if len(hostnames) != len(ports) {
+		return "", errors.New("The number of hostnames (" + strconv.Itoa(len(hostnames)) +
+			") does not match the number of ports (" + strconv.Itoa(len(ports)) + ")")
+	}
+
+	connNames := make([]string, len(hostnames))
+	for i, hostname := range hostnames {
+		connNames[i] = hostname + "(" + strconv.Itoa(ports[i]) + ")"
+	}
 
+	return strings.Join(connNames, ","), nil
 }
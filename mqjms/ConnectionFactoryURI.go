@@ -0,0 +1,542 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The scheme used by connection factory URIs.
+const uriScheme = "mq://"
+
+// The string that replaces the password when a ConnectionFactory is printed.
+const maskedPassword = "********"
+
+// The names of the query parameters in a connection factory URI.
+const (
+	uriParamChannel          = "channel"
+	uriParamTransport        = "transport"
+	uriParamCipher           = "cipher"
+	uriParamClientAuth       = "clientAuth"
+	uriParamKeyRepository    = "keyRepository"
+	uriParamCertLabel        = "certLabel"
+	uriParamPeerName         = "peerName"
+	uriParamKeyResetCount    = "keyResetCount"
+	uriParamFipsRequired     = "fipsRequired"
+	uriParamCCDT             = "ccdt"
+	uriParamReconnect        = "reconnect"
+	uriParamReconnectTimeout = "reconnectTimeout"
+	uriParamConnectTimeout   = "connectTimeout"
+	uriParamApplName         = "applName"
+	uriParamMaxMsgLength     = "maxMsgLength"
+	uriParamKeepAlive        = "keepAliveInterval"
+	uriParamSharingConvs     = "sharingConversations"
+	uriParamHandleCacheSize  = "handleCacheSize"
+	uriParamDelayQueue       = "deliveryDelayQueue"
+)
+
+// The values of the transport query parameter.
+const (
+	uriTransportClient   = "client"
+	uriTransportBindings = "bindings"
+)
+
+// The values of the reconnect query parameter, indexed by the corresponding
+// ClientReconnectOptions value.
+var uriReconnectValues = map[int]string{
+	ClientReconnect_AS_DEF:    "asdef",
+	ClientReconnect_DISABLED:  "disabled",
+	ClientReconnect_RECONNECT: "reconnect",
+	ClientReconnect_QMGR:      "qmgr",
+}
+
+// CreateConnectionFactoryFromURI is a utility method that creates a JMS
+// ConnectionFactory object that is populated with properties from a URI
+// string, which is convenient for applications that are configured through
+// a single environment variable or command line argument.
+//
+// The URI has the following format, where every part is optional apart from
+// the "mq://" scheme;
+//
+//	mq://user:password@host1:1414,host2:1414/QMName?channel=APP.SVRCONN&cipher=ANY_TLS12
+//
+// Multiple hosts are used to populate the ConnectionNameList, and a host without
+// a port uses the default MQ port of 1414. The following query parameters are
+// supported;
+//   - channel           the name of the server connection channel
+//   - transport         "client" (the default) or "bindings"
+//   - cipher            the TLS CipherSpec, for example ANY_TLS12
+//   - clientAuth        "NONE" or "REQUIRED"
+//   - keyRepository     the path to the key repository, without the file extension
+//   - certLabel         the label of the client certificate in the key repository
+//   - peerName          the distinguished name that the queue manager certificate must match
+//   - keyResetCount     the number of bytes after which the TLS secret key is renegotiated
+//   - fipsRequired      "true" to only use FIPS-certified cryptography
+//   - ccdt              the URL or path of a client channel definition table
+//   - reconnect         "asdef", "disabled", "reconnect" or "qmgr"
+//   - reconnectTimeout  the client reconnect timeout in seconds
+//   - connectTimeout    the connect timeout in milliseconds
+//   - applName          the name that identifies the application to the queue manager
+//   - maxMsgLength, keepAliveInterval, sharingConversations
+//     the channel attributes, as described on ConnectionFactoryImpl
+//   - handleCacheSize   the number of queues that each context keeps open for sending
+//   - deliveryDelayQueue the staging queue for messages sent with a delivery delay
+//
+// The user name, password and queue manager name should be percent-encoded
+// if they contain any reserved characters such as "@", ":" or "/".
+func CreateConnectionFactoryFromURI(uri string) (cf ConnectionFactoryImpl, err error) {
+
+	if !strings.HasPrefix(strings.ToLower(uri), uriScheme) {
+		return ConnectionFactoryImpl{}, errors.New("Connection URI must start with " + uriScheme)
+	}
+	remainder := uri[len(uriScheme):]
+
+	// Separate out the query string, if there is one.
+	var rawQuery string
+	if idx := strings.Index(remainder, "?"); idx >= 0 {
+		rawQuery = remainder[idx+1:]
+		remainder = remainder[:idx]
+	}
+
+	// Separate the authority (credentials and hosts) from the path, which
+	// contains the queue manager name.
+	authority := remainder
+	var path string
+	if idx := strings.Index(remainder, "/"); idx >= 0 {
+		authority = remainder[:idx]
+		path = remainder[idx+1:]
+	}
+
+	// Parse the credentials, if there are any. The url package decodes them
+	// the same way that URI encodes them, so that a user name or password
+	// containing characters such as ':' or '@' is read back correctly.
+	hostList := authority
+	if idx := strings.LastIndex(authority, "@"); idx >= 0 {
+		hostList = authority[idx+1:]
+
+		userURL, userErr := url.Parse(uriScheme + authority[:idx] + "@localhost")
+		if userErr != nil {
+			// Only report the underlying problem, as the URL that the url
+			// package includes in the error contains the password.
+			if urlErr, ok := userErr.(*url.Error); ok {
+				userErr = urlErr.Err
+			}
+			return ConnectionFactoryImpl{}, errors.New("Invalid credentials in connection URI: " + userErr.Error())
+		}
+
+		cf.UserName = userURL.User.Username()
+		cf.Password, _ = userURL.User.Password()
+
+		if cf.UserName == "" {
+			return ConnectionFactoryImpl{}, errors.New("Connection URI contains a password but no user name")
+		}
+	}
+
+	// Parse the list of hosts.
+	var hostnames []string
+	var ports []int
+	if hostList != "" {
+		for _, hostPort := range strings.Split(hostList, ",") {
+
+			hostname, port, hostErr := parseURIHost(hostPort)
+			if hostErr != nil {
+				return ConnectionFactoryImpl{}, hostErr
+			}
+
+			hostnames = append(hostnames, hostname)
+			ports = append(ports, port)
+		}
+	}
+
+	if len(hostnames) == 1 {
+		cf.Hostname = hostnames[0]
+		cf.PortNumber = ports[0]
+	} else if len(hostnames) > 1 {
+		cf.ConnectionNameList, _ = CreateConnectionNameList(hostnames, ports)
+	}
+
+	// The path contains the queue manager name, which can be empty in order to
+	// connect to the default queue manager.
+	if strings.Contains(path, "/") {
+		return ConnectionFactoryImpl{}, errors.New("Connection URI path must only contain the queue manager name: /" + path)
+	}
+
+	cf.QMName, err = url.PathUnescape(path)
+	if err != nil {
+		return ConnectionFactoryImpl{}, errors.New("Invalid queue manager name in connection URI: " + err.Error())
+	}
+
+	// Finally apply the query parameters.
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return ConnectionFactoryImpl{}, errors.New("Invalid query string in connection URI: " + err.Error())
+	}
+
+	err = applyURIParams(&cf, params)
+	if err != nil {
+		return ConnectionFactoryImpl{}, err
+	}
+
+	// Check that the combination of values makes sense.
+	if cf.TransportType == TransportType_BINDINGS && len(hostnames) > 0 {
+		return ConnectionFactoryImpl{}, errors.New("Connection URI must not contain a host when transport=" + uriTransportBindings)
+	}
+
+	if cf.TransportType == TransportType_CLIENT && len(hostnames) == 0 && cf.CCDTURL == "" {
+		return ConnectionFactoryImpl{}, errors.New("Connection URI must contain a host, or the " + uriParamCCDT +
+			" parameter, when transport=" + uriTransportClient)
+	}
+
+	return cf, nil
+
+}
+
+// parseURIHost splits a host from a connection URI into the hostname and port
+// number, applying the default port if one is not specified.
+func parseURIHost(hostPort string) (string, int, error) {
+
+	if hostPort == "" {
+		return "", 0, errors.New("Connection URI contains an empty host")
+	}
+
+	// Use the standard library to deal with the IPv6 form "[::1]:1414".
+	hostname, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		if strings.Contains(hostPort, ":") && !strings.HasPrefix(hostPort, "[") {
+			return "", 0, errors.New("Invalid host \"" + hostPort + "\" in connection URI")
+		}
+
+		// There is no port, so use the default.
+		return strings.Trim(hostPort, "[]"), 1414, nil
+	}
+
+	if hostname == "" {
+		return "", 0, errors.New("Connection URI contains a port with no hostname: " + hostPort)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, errors.New("Invalid port \"" + portStr + "\" for host \"" + hostname + "\" in connection URI")
+	}
+
+	return hostname, port, nil
+}
+
+// applyURIParams populates the ConnectionFactory from the query parameters of
+// a connection URI.
+func applyURIParams(cf *ConnectionFactoryImpl, params url.Values) error {
+
+	for name, values := range params {
+
+		if len(values) != 1 {
+			return errors.New("Connection URI parameter " + name + " must only be specified once")
+		}
+		value := values[0]
+
+		switch name {
+		case uriParamChannel:
+			cf.ChannelName = value
+
+		case uriParamTransport:
+			transportType, ok := parseTransportTypeName(value)
+			if !ok {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected " + uriTransportClient + " or " + uriTransportBindings)
+			}
+			cf.TransportType = transportType
+
+		case uriParamCipher:
+			cf.TLSCipherSpec = value
+
+		case uriParamClientAuth:
+			if value != TLSClientAuth_NONE && value != TLSClientAuth_REQUIRED {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected " + TLSClientAuth_NONE + " or " + TLSClientAuth_REQUIRED)
+			}
+			cf.TLSClientAuth = value
+
+		case uriParamKeyRepository:
+			cf.KeyRepository = value
+
+		case uriParamCertLabel:
+			cf.CertificateLabel = value
+
+		case uriParamPeerName:
+			cf.TLSPeerName = value
+
+		case uriParamKeyResetCount:
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected a number of bytes")
+			}
+			cf.TLSKeyResetCount = count
+
+		case uriParamFipsRequired:
+			fips, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected true or false")
+			}
+			cf.TLSFipsRequired = fips
+
+		case uriParamCCDT:
+			cf.CCDTURL = value
+
+		case uriParamReconnect:
+			reconnectOptions, ok := parseReconnectOptionName(value)
+			if !ok {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected one of asdef, disabled, reconnect or qmgr")
+			}
+			cf.ClientReconnectOptions = reconnectOptions
+
+		case uriParamReconnectTimeout:
+			timeout, err := strconv.Atoi(value)
+			if err != nil || timeout < 0 {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected a non-negative number of seconds")
+			}
+			cf.ClientReconnectTimeout = timeout
+
+		case uriParamConnectTimeout:
+			timeout, err := strconv.Atoi(value)
+			if err != nil || timeout < 0 {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected a non-negative number of milliseconds")
+			}
+			cf.ConnectTimeout = timeout
+
+		case uriParamApplName:
+			cf.ApplicationName = value
+
+		case uriParamHandleCacheSize:
+			size, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected a number")
+			}
+			cf.HandleCacheSize = size
+
+		case uriParamDelayQueue:
+			cf.DeliveryDelayQueue = value
+
+		case uriParamMaxMsgLength, uriParamKeepAlive, uriParamSharingConvs:
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("Invalid value \"" + value + "\" for connection URI parameter " + name +
+					", expected a number")
+			}
+			*cf.channelAttribute(name) = number
+
+		default:
+			return errors.New("Unknown connection URI parameter: " + name)
+		}
+	}
+
+	return nil
+}
+
+// channelAttribute returns a pointer to the property of the ConnectionFactory
+// that holds the channel attribute with the given URI parameter name.
+func (cf *ConnectionFactoryImpl) channelAttribute(name string) *int {
+
+	switch name {
+	case uriParamMaxMsgLength:
+		return &cf.MaxMsgLength
+	case uriParamKeepAlive:
+		return &cf.KeepAliveInterval
+	default:
+		return &cf.SharingConversations
+	}
+}
+
+// parseTransportTypeName converts the name of a transport type ("client" or
+// "bindings") into the corresponding TransportType value, returning false if
+// the name is not recognised.
+func parseTransportTypeName(value string) (int, bool) {
+
+	switch strings.ToLower(value) {
+	case uriTransportClient:
+		return TransportType_CLIENT, true
+	case uriTransportBindings:
+		return TransportType_BINDINGS, true
+	}
+
+	return 0, false
+}
+
+// parseReconnectOptionName converts the name of a reconnect option, such as
+// "qmgr", into the corresponding ClientReconnectOptions value, returning false
+// if the name is not recognised.
+func parseReconnectOptionName(value string) (int, bool) {
+
+	for option, optionValue := range uriReconnectValues {
+		if strings.ToLower(value) == optionValue {
+			return option, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns the connection URI that represents this ConnectionFactory,
+// in the format accepted by CreateConnectionFactoryFromURI.
+//
+// Note that the URI includes the password, so should be treated with the
+// same care as the password itself. Use String to obtain a representation
+// that is safe to be written to a log.
+func (cf ConnectionFactoryImpl) URI() string {
+	return cf.toURI(false)
+}
+
+// String returns the connection URI that represents this ConnectionFactory,
+// with the password masked so that it is safe to be written to a log.
+func (cf ConnectionFactoryImpl) String() string {
+	return cf.toURI(true)
+}
+
+// toURI builds the connection URI for this ConnectionFactory, optionally
+// masking the password.
+func (cf ConnectionFactoryImpl) toURI(maskPassword bool) string {
+
+	var sb strings.Builder
+	sb.WriteString(uriScheme)
+
+	if cf.UserName != "" {
+		if cf.Password == "" {
+			sb.WriteString(url.User(cf.UserName).String())
+		} else if maskPassword {
+			// The mask is written without escaping so that it is readable.
+			sb.WriteString(url.User(cf.UserName).String())
+			sb.WriteString(":")
+			sb.WriteString(maskedPassword)
+		} else {
+			sb.WriteString(url.UserPassword(cf.UserName, cf.Password).String())
+		}
+		sb.WriteString("@")
+	}
+
+	if cf.TransportType == TransportType_CLIENT {
+		if cf.ConnectionNameList != "" {
+			sb.WriteString(connectionNameListToURIHosts(cf.ConnectionNameList))
+		} else if cf.Hostname != "" {
+			sb.WriteString(net.JoinHostPort(cf.Hostname, strconv.Itoa(cf.PortNumber)))
+		}
+	}
+
+	sb.WriteString("/")
+	sb.WriteString(url.PathEscape(cf.QMName))
+
+	params := url.Values{}
+	if cf.ChannelName != "" {
+		params.Set(uriParamChannel, cf.ChannelName)
+	}
+	if cf.TransportType == TransportType_BINDINGS {
+		params.Set(uriParamTransport, uriTransportBindings)
+	}
+	if cf.TLSCipherSpec != "" {
+		params.Set(uriParamCipher, cf.TLSCipherSpec)
+	}
+	if cf.TLSClientAuth != "" {
+		params.Set(uriParamClientAuth, cf.TLSClientAuth)
+	}
+	if cf.KeyRepository != "" {
+		params.Set(uriParamKeyRepository, cf.KeyRepository)
+	}
+	if cf.CertificateLabel != "" {
+		params.Set(uriParamCertLabel, cf.CertificateLabel)
+	}
+	if cf.TLSPeerName != "" {
+		params.Set(uriParamPeerName, cf.TLSPeerName)
+	}
+	if cf.TLSKeyResetCount != 0 {
+		params.Set(uriParamKeyResetCount, strconv.Itoa(cf.TLSKeyResetCount))
+	}
+	if cf.TLSFipsRequired {
+		params.Set(uriParamFipsRequired, "true")
+	}
+	if cf.CCDTURL != "" {
+		params.Set(uriParamCCDT, cf.CCDTURL)
+	}
+	if cf.ClientReconnectOptions != ClientReconnect_AS_DEF {
+		params.Set(uriParamReconnect, uriReconnectValues[cf.ClientReconnectOptions])
+	}
+	if cf.ClientReconnectTimeout != 0 {
+		params.Set(uriParamReconnectTimeout, strconv.Itoa(cf.ClientReconnectTimeout))
+	}
+	if cf.ConnectTimeout != 0 {
+		params.Set(uriParamConnectTimeout, strconv.Itoa(cf.ConnectTimeout))
+	}
+	if cf.ApplicationName != "" {
+		params.Set(uriParamApplName, cf.ApplicationName)
+	}
+	for _, name := range []string{uriParamMaxMsgLength, uriParamKeepAlive, uriParamSharingConvs} {
+		if value := *cf.channelAttribute(name); value != 0 {
+			params.Set(name, strconv.Itoa(value))
+		}
+	}
+	if cf.HandleCacheSize != 0 {
+		params.Set(uriParamHandleCacheSize, strconv.Itoa(cf.HandleCacheSize))
+	}
+	if cf.DeliveryDelayQueue != "" {
+		params.Set(uriParamDelayQueue, cf.DeliveryDelayQueue)
+	}
+
+	if len(params) > 0 {
+		sb.WriteString("?")
+		sb.WriteString(encodeURIParams(params))
+	}
+
+	return sb.String()
+}
+
+// encodeURIParams encodes the query parameters in a stable order, keeping the
+// characters that are commonly used in MQ object names readable.
+func encodeURIParams(params url.Values) string {
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	encoded := make([]string, len(names))
+	for i, name := range names {
+		encoded[i] = name + "=" + url.QueryEscape(params.Get(name))
+	}
+
+	return strings.Join(encoded, "&")
+}
+
+// connectionNameListToURIHosts converts a connection name list in the MQ
+// format "host1(1414),host2(1414)" into the host list of a connection URI.
+func connectionNameListToURIHosts(connNameList string) string {
+
+	connNames := strings.Split(connNameList, ",")
+	hosts := make([]string, len(connNames))
+
+	for i, connName := range connNames {
+		connName = strings.TrimSpace(connName)
+
+		if idx := strings.Index(connName, "("); idx >= 0 && strings.HasSuffix(connName, ")") {
+			hosts[i] = net.JoinHostPort(connName[:idx], connName[idx+1:len(connName)-1])
+		} else {
+			hosts[i] = connName
+		}
+	}
+
+	return strings.Join(hosts, ",")
+}
@@ -0,0 +1,282 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"errors"
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The maximum length of an MQ queue manager name.
+const maxQMNameLength = 48
+
+// The maximum length of the SSLPEER attribute of a channel.
+const maxPeerNameLength = 1024
+
+// The maximum length of the application name of a connection.
+const maxApplNameLength = 28
+
+// The largest values of the channel attributes that can be configured on a
+// ConnectionFactory.
+const (
+	maxMsgLength            = 104857600
+	maxKeepAliveInterval    = 99999
+	maxSharingConversations = 999999999
+)
+
+// Validate checks the properties of this ConnectionFactory and returns a list
+// of every problem that it finds, or an empty list if the configuration is
+// valid. This allows an application to report all of its configuration errors
+// at once, rather than finding them one at a time as failures to connect.
+//
+// Validate is called automatically by CreateContext, but can also be called
+// directly, for example to check the configuration when an application starts.
+func (cf ConnectionFactoryImpl) Validate() []error {
+
+	var errs []error
+	addError := func(msg string) {
+		errs = append(errs, errors.New(msg))
+	}
+
+	if len(cf.QMName) > maxQMNameLength {
+		addError("QMName must not be longer than " + strconv.Itoa(maxQMNameLength) + " characters")
+	}
+
+	if cf.CredentialsProvider != nil {
+		if cf.UserName != "" || cf.Password != "" {
+			addError("UserName and Password must not be specified when a CredentialsProvider is specified")
+		}
+		// The default of ClientReconnect_AS_DEF is rejected as well, because
+		// the channel definition or mqclient.ini could enable reconnection.
+		if cf.TransportType == TransportType_CLIENT && cf.ClientReconnectOptions != ClientReconnect_DISABLED {
+			addError("ClientReconnectOptions must be ClientReconnect_DISABLED when a CredentialsProvider " +
+				"is specified, because the MQ client would reconnect using the original credentials")
+		}
+	} else if cf.Password != "" && cf.UserName == "" {
+		addError("UserName must be specified when a Password is specified")
+	}
+
+	if len(cf.ApplicationName) > maxApplNameLength {
+		addError("ApplicationName must not be longer than " + strconv.Itoa(maxApplNameLength) + " characters")
+	}
+
+	if cf.ConnectTimeout < 0 {
+		addError("ConnectTimeout must not be negative")
+	}
+
+	if cf.HandleCacheSize < HandleCacheSize_DISABLED {
+		addError("HandleCacheSize must not be negative, apart from HandleCacheSize_DISABLED (" +
+			strconv.Itoa(HandleCacheSize_DISABLED) + ")")
+	}
+
+	switch cf.TransportType {
+	case TransportType_CLIENT:
+		errs = append(errs, cf.validateClient()...)
+
+	case TransportType_BINDINGS:
+		// None of the network settings are used by a bindings connection, so
+		// they are ignored rather than checked. This allows a ConnectionFactory
+		// that was configured for a client connection to be switched to bindings.
+
+	default:
+		addError("TransportType must be TransportType_CLIENT or TransportType_BINDINGS, not " +
+			strconv.Itoa(cf.TransportType))
+	}
+
+	return errs
+}
+
+// validateClient checks the properties that are used by a client connection.
+func (cf ConnectionFactoryImpl) validateClient() []error {
+
+	var errs []error
+	addError := func(msg string) {
+		errs = append(errs, errors.New(msg))
+	}
+
+	if cf.CCDTURL != "" {
+		// The channel definition comes from the CCDT, so any settings that
+		// would otherwise be used to build it are ignored.
+		if cf.Hostname != "" {
+			addError("Hostname must not be specified when a CCDTURL is specified")
+		}
+		if cf.ConnectionNameList != "" {
+			addError("ConnectionNameList must not be specified when a CCDTURL is specified")
+		}
+		if cf.ChannelName != "" {
+			addError("ChannelName must not be specified when a CCDTURL is specified")
+		}
+		channelAttrs := []struct {
+			name  string
+			isSet bool
+		}{
+			{"MaxMsgLength", cf.MaxMsgLength != 0},
+			{"KeepAliveInterval", cf.KeepAliveInterval != 0},
+			{"SharingConversations", cf.SharingConversations != 0},
+		}
+		for _, attr := range channelAttrs {
+			if attr.isSet {
+				addError(attr.name + " must not be specified when a CCDTURL is specified, as it is read from the CCDT")
+			}
+		}
+	} else {
+		if cf.ChannelName == "" {
+			addError("ChannelName must be specified for a client connection")
+		} else if len(cf.ChannelName) > maxChannelNameLength {
+			addError("ChannelName must not be longer than " + strconv.Itoa(maxChannelNameLength) + " characters")
+		}
+
+		if cf.Hostname == "" && cf.ConnectionNameList == "" {
+			addError("Either Hostname or ConnectionNameList must be specified for a client connection")
+		}
+	}
+
+	if cf.Hostname != "" && cf.ConnectionNameList != "" {
+		addError("Hostname and ConnectionNameList must not both be specified")
+	}
+
+	if cf.Hostname != "" && (cf.PortNumber < 1 || cf.PortNumber > 65535) {
+		addError("PortNumber must be between 1 and 65535, not " + strconv.Itoa(cf.PortNumber))
+	}
+
+	if cf.ConnectionNameList != "" {
+		for _, connName := range strings.Split(cf.ConnectionNameList, ",") {
+			connName = strings.TrimSpace(connName)
+			if connName == "" {
+				addError("ConnectionNameList must not contain an empty connection name")
+				continue
+			}
+
+			if _, _, err := parseConnectionName(connName); err != nil {
+				addError("ConnectionNameList contains an invalid connection name: " + err.Error())
+			}
+		}
+	}
+
+	// Channel attributes
+	if cf.MaxMsgLength < 0 || cf.MaxMsgLength > maxMsgLength {
+		addError("MaxMsgLength must be between 0 and " + strconv.Itoa(maxMsgLength) +
+			", not " + strconv.Itoa(cf.MaxMsgLength))
+	}
+
+	if cf.KeepAliveInterval != KeepAliveInterval_AUTO &&
+		(cf.KeepAliveInterval < 0 || cf.KeepAliveInterval > maxKeepAliveInterval) {
+		addError("KeepAliveInterval must be KeepAliveInterval_AUTO or between 0 and " +
+			strconv.Itoa(maxKeepAliveInterval) + ", not " + strconv.Itoa(cf.KeepAliveInterval))
+	}
+
+	if cf.SharingConversations != SharingConversations_NONE &&
+		(cf.SharingConversations < 0 || cf.SharingConversations > maxSharingConversations) {
+		addError("SharingConversations must be SharingConversations_NONE or between 0 and " +
+			strconv.Itoa(maxSharingConversations) + ", not " + strconv.Itoa(cf.SharingConversations))
+	}
+
+	// TLS settings. The TLSCipherSpec itself is checked by the MQ client and
+	// the queue manager, which know which CipherSpecs they support (see
+	// next-features.txt).
+	switch cf.TLSClientAuth {
+	case "", TLSClientAuth_NONE:
+	case TLSClientAuth_REQUIRED:
+		if cf.TLSCipherSpec == "" && cf.CCDTURL == "" {
+			addError("TLSClientAuth " + TLSClientAuth_REQUIRED + " requires a TLSCipherSpec to be specified")
+		}
+	default:
+		addError("TLSClientAuth must be " + TLSClientAuth_NONE + " or " + TLSClientAuth_REQUIRED +
+			", not \"" + cf.TLSClientAuth + "\"")
+	}
+
+	if cf.TLSCipherSpec == "" && cf.CCDTURL == "" {
+		if cf.TLSPeerName != "" {
+			addError("TLSPeerName requires a TLSCipherSpec to be specified")
+		}
+		if cf.TLSFipsRequired {
+			addError("TLSFipsRequired requires a TLSCipherSpec to be specified")
+		}
+	}
+
+	if cf.TLSPeerName != "" {
+		if len(cf.TLSPeerName) > maxPeerNameLength {
+			addError("TLSPeerName must not be longer than " + strconv.Itoa(maxPeerNameLength) + " characters")
+		} else if !strings.Contains(cf.TLSPeerName, "=") {
+			addError("TLSPeerName must be a distinguished name such as \"CN=QM1,O=IBM\", not \"" +
+				cf.TLSPeerName + "\"")
+		}
+	}
+
+	if cf.TLSKeyResetCount != 0 &&
+		(cf.TLSKeyResetCount < TLSKeyResetCount_MIN || cf.TLSKeyResetCount > TLSKeyResetCount_MAX) {
+		addError("TLSKeyResetCount must be zero or between " + strconv.Itoa(TLSKeyResetCount_MIN) +
+			" and " + strconv.Itoa(TLSKeyResetCount_MAX) + ", not " + strconv.Itoa(cf.TLSKeyResetCount))
+	}
+
+	if cf.CertificateLabel != "" && cf.KeyRepository == "" {
+		addError("CertificateLabel requires a KeyRepository to be specified")
+	}
+
+	if cf.KeyRepository != "" {
+		errs = append(errs, validateKeyRepository(cf.KeyRepository)...)
+	}
+
+	// Reconnection settings
+	switch cf.ClientReconnectOptions {
+	case ClientReconnect_AS_DEF, ClientReconnect_RECONNECT, ClientReconnect_QMGR:
+	case ClientReconnect_DISABLED:
+		if cf.ClientReconnectTimeout != 0 {
+			addError("ClientReconnectTimeout must not be specified when reconnection is disabled")
+		}
+	default:
+		addError("ClientReconnectOptions must be one of the ClientReconnect_* constants, not " +
+			strconv.Itoa(cf.ClientReconnectOptions))
+	}
+
+	if cf.ClientReconnectTimeout < 0 {
+		addError("ClientReconnectTimeout must not be negative")
+	}
+
+	return errs
+}
+
+// validateKeyRepository checks that the key database exists, along with the
+// stash file that contains its password. The key repository is specified
+// without its file extension, in the same way as for the MQ client.
+func validateKeyRepository(keyRepository string) []error {
+
+	var errs []error
+
+	stem := strings.TrimSuffix(keyRepository, ".kdb")
+
+	for _, fileName := range []string{stem + ".kdb", stem + ".sth"} {
+		info, err := os.Stat(fileName)
+		if err != nil {
+			errs = append(errs, errors.New("KeyRepository file "+fileName+" cannot be read: "+err.Error()))
+		} else if info.IsDir() {
+			errs = append(errs, errors.New("KeyRepository file "+fileName+" is a directory"))
+		}
+	}
+
+	return errs
+}
+
+// createInvalidConfigException returns the error that is given to an
+// application that attempts to connect using a ConnectionFactory with an
+// invalid configuration, describing each of the problems that were found.
+func createInvalidConfigException(errs []error) jms20subset.JMSException {
+
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+
+	return jms20subset.CreateJMSException("Invalid ConnectionFactory configuration: "+strings.Join(msgs, "; "),
+		"MQJMS0005", errs[0])
+}
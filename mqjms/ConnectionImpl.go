@@ -0,0 +1,442 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang/ibmmq"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// connectionImpl represents the connection to the queue manager that is
+// shared between a context created by a ConnectionFactory and any further
+// contexts that are created from it using JMSContext.CreateContext.
+//
+// All of the contexts use the same connection handle, which was created with
+// MQCNO_HANDLE_SHARE_BLOCK so that the MQ client serialises the calls that
+// they make on it. The handle is disconnected when the last context using the
+// connection is closed.
+type connectionImpl struct {
+	cf         ConnectionFactoryImpl
+	qMgr       ibmmq.MQQueueManager
+	events     *connectionEvents
+	asyncSends *asyncSendTracker
+
+	// Held while a batch of messages is sent in units of work, because a commit
+	// or backout applies to all of the work done using the connection handle,
+	// whichever context it was done by.
+	syncpointMutex sync.Mutex
+
+	// Protects the attributes below.
+	mutex    sync.Mutex
+	refCount int
+
+	// Set when a call fails in a way that means that the connection handle
+	// can no longer be used, for example with MQRC_CONNECTION_BROKEN.
+	failure jms20subset.JMSException
+}
+
+// newConnection wraps a connection handle that has just been created, for
+// use by a single context to begin with.
+func newConnection(cf ConnectionFactoryImpl, qMgr ibmmq.MQQueueManager) *connectionImpl {
+	return &connectionImpl{
+		cf:         cf,
+		qMgr:       qMgr,
+		events:     newConnectionEvents(cf, qMgr),
+		asyncSends: newAsyncSendTracker(qMgr),
+		refCount:   1,
+	}
+}
+
+// addContext registers an additional context that uses this connection,
+// returning false if the connection has already been closed.
+func (conn *connectionImpl) addContext() bool {
+
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	if conn.refCount == 0 {
+		return false
+	}
+
+	conn.refCount++
+	return true
+}
+
+// removeContext deregisters a context that has been closed, and disconnects
+// the connection handle once there are no more contexts using it.
+func (conn *connectionImpl) removeContext() {
+
+	conn.mutex.Lock()
+	conn.refCount--
+	lastContext := conn.refCount == 0
+	conn.mutex.Unlock()
+
+	if !lastContext {
+		return
+	}
+
+	conn.events.close()
+	conn.asyncSends.close()
+
+	if (ibmmq.MQQueueManager{}) != conn.qMgr {
+		conn.qMgr.Disc()
+	}
+}
+
+// checkState returns an error if the connection can no longer be used, either
+// because a call made using it found that it is broken or because the client
+// failed to reconnect to a queue manager, or nil otherwise.
+func (conn *connectionImpl) checkState() jms20subset.JMSException {
+
+	conn.mutex.Lock()
+	failure := conn.failure
+	conn.mutex.Unlock()
+
+	if failure != nil {
+		return failure
+	}
+
+	return conn.events.checkReconnectState()
+}
+
+// noteError records the error from a call made using the connection handle if
+// it means that the handle can no longer be used, so that the contexts using
+// it report the problem from then on instead of making further calls, and a
+// PooledConnectionFactory does not hand them out again. This does not rely on
+// the event handler, which is only registered if it is needed for
+// reconnection or an exception listener.
+func (conn *connectionImpl) noteError(err error) {
+
+	mqret, ok := err.(*ibmmq.MQReturn)
+	if !ok {
+		return
+	}
+
+	switch mqret.MQRC {
+	case ibmmq.MQRC_CONNECTION_BROKEN, ibmmq.MQRC_Q_MGR_NOT_AVAILABLE, ibmmq.MQRC_HCONN_ERROR,
+		ibmmq.MQRC_RECONNECT_FAILED:
+
+	case ibmmq.MQRC_CONNECTION_QUIESCING, ibmmq.MQRC_CONNECTION_STOPPING,
+		ibmmq.MQRC_Q_MGR_QUIESCING, ibmmq.MQRC_Q_MGR_STOPPING:
+		// The client moves a reconnectable connection to another queue
+		// manager (or the same one once it restarts) by itself.
+		if conn.cf.isReconnectEnabled() {
+			return
+		}
+
+	default:
+		return
+	}
+
+	conn.mutex.Lock()
+	if conn.failure == nil {
+		conn.failure = createMQException(err)
+	}
+	conn.mutex.Unlock()
+}
+
+// connectionEvents receives the events that the MQ client reports for a
+// connection handle, such as the connection being broken or reconnected, and
+// passes them to the exception listeners of the contexts that share the handle.
+type connectionEvents struct {
+	cf   ConnectionFactoryImpl
+	qMgr ibmmq.MQQueueManager
+
+	// Protects the attributes below.
+	mutex     sync.Mutex
+	closed    bool
+	listeners map[*ContextImpl]func(jms20subset.JMSException)
+
+	// Objects used to receive the events from the queue manager, which are
+	// only created when they are first needed.
+	object ibmmq.MQObject
+	events chan jms20subset.JMSException
+	done   chan struct{}
+
+	// Used to enforce the reconnect timeout when automatic client reconnection
+	// is enabled, and to record the failure if reconnection does not succeed.
+	reconnectTimer *time.Timer
+	reconnectErr   jms20subset.JMSException
+}
+
+// newConnectionEvents creates the object that handles the events for a
+// connection handle. If the ConnectionFactory allows automatic reconnection
+// then the event handler is registered straight away, so that the reconnect
+// timeout is applied whether or not the application sets an exception
+// listener.
+func newConnectionEvents(cf ConnectionFactoryImpl, qMgr ibmmq.MQQueueManager) *connectionEvents {
+
+	ce := &connectionEvents{
+		cf:   cf,
+		qMgr: qMgr,
+		done: make(chan struct{}),
+	}
+
+	if cf.isReconnectEnabled() {
+		ce.mutex.Lock()
+		eventErr := ce.register()
+		ce.mutex.Unlock()
+
+		if eventErr != nil {
+			// Reconnection is carried out by the MQ client regardless, so
+			// this only affects the reporting of the events.
+			log.Print("Unable to register for reconnection events: " + eventErr.GetReason())
+		}
+	}
+
+	return ce
+}
+
+// setListener stores the function that is called for the given context when
+// the queue manager reports an event that affects the connection handle, and
+// registers to receive those events the first time that a listener is set.
+// Passing a nil listener removes the listener of the context.
+func (ce *connectionEvents) setListener(ctx *ContextImpl, listener func(jms20subset.JMSException)) jms20subset.JMSException {
+
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
+
+	if ce.closed {
+		return createIllegalStateException("JMSContext")
+	}
+
+	if listener == nil {
+		delete(ce.listeners, ctx)
+		return nil
+	}
+
+	if ce.events == nil {
+		err := ce.register()
+		if err != nil {
+			return err
+		}
+	}
+
+	if ce.listeners == nil {
+		ce.listeners = make(map[*ContextImpl]func(jms20subset.JMSException))
+	}
+	ce.listeners[ctx] = listener
+
+	return nil
+}
+
+// register asks the MQ client to call the event handler for this connection
+// handle. It must be called while holding the mutex.
+func (ce *connectionEvents) register() jms20subset.JMSException {
+
+	// This version of the MQ Golang binding only exposes MQCB for an object
+	// handle, so we open the queue manager object to register the handler
+	// with. The MQ client reports events for the connection handle as a
+	// whole, and the binding passes them to the handler that is registered
+	// for that connection handle, so it is registered once for each connection.
+	//
+	// MQCTL is deliberately not used to start the connection, because event
+	// handlers are called without it and a started connection handle cannot
+	// be used for the synchronous calls that the rest of this package makes
+	// (they fail with MQRC_HCONN_ASYNC_ACTIVE).
+	mqod := ibmmq.NewMQOD()
+	mqod.ObjectType = ibmmq.MQOT_Q_MGR
+
+	var err error
+	ce.object, err = ce.qMgr.Open(mqod, ibmmq.MQOO_INQUIRE)
+
+	if err == nil {
+		err = ce.object.CB(ibmmq.MQOP_REGISTER, newEventHandlerCBD(ce.handleEvent), ibmmq.NewMQMD(), ibmmq.NewMQGMO())
+
+		if err != nil {
+			ce.object.Close(0)
+		}
+	}
+
+	if err != nil {
+		ce.object = ibmmq.MQObject{}
+
+		rcInt := int(err.(*ibmmq.MQReturn).MQRC)
+		errCode := strconv.Itoa(rcInt)
+		reason := ibmmq.MQItoString("RC", rcInt)
+		return jms20subset.CreateJMSException(reason, errCode, err)
+	}
+
+	// Events are passed to the listener on a separate goroutine so that the
+	// application does not run its own logic on the thread that the MQ client
+	// uses to deliver the event, while still delivering them in order.
+	ce.events = make(chan jms20subset.JMSException, 10)
+	go ce.dispatch()
+
+	return nil
+}
+
+// close stops listening for events, and must be called before the connection
+// handle is disconnected. Calling close more than once has no effect.
+func (ce *connectionEvents) close() {
+
+	ce.mutex.Lock()
+	if ce.closed {
+		ce.mutex.Unlock()
+		return
+	}
+	ce.closed = true
+	registered := ce.events != nil
+	if ce.reconnectTimer != nil {
+		ce.reconnectTimer.Stop()
+		ce.reconnectTimer = nil
+	}
+	ce.mutex.Unlock()
+
+	if registered {
+		ce.object.CB(ibmmq.MQOP_DEREGISTER, newEventHandlerCBD(nil), ibmmq.NewMQMD(), ibmmq.NewMQGMO())
+		ce.object.Close(0)
+	}
+
+	close(ce.done)
+}
+
+// newEventHandlerCBD creates the callback descriptor used to register (or
+// deregister) the event handler for a connection handle.
+func newEventHandlerCBD(handler ibmmq.MQCB_FUNCTION) *ibmmq.MQCBD {
+
+	cbd := ibmmq.NewMQCBD()
+	cbd.CallbackType = ibmmq.MQCBT_EVENT_HANDLER
+	cbd.CallbackFunction = handler
+
+	return cbd
+}
+
+// handleEvent is called by the MQ client when an event occurs that affects
+// the connection handle, for example when it is broken, the queue manager is
+// quiescing or the client is reconnecting.
+func (ce *connectionEvents) handleEvent(qMgr *ibmmq.MQQueueManager, obj *ibmmq.MQObject,
+	md *ibmmq.MQMD, gmo *ibmmq.MQGMO, buffer []byte, cbc *ibmmq.MQCBC, mqret *ibmmq.MQReturn) {
+
+	if cbc.CallType != ibmmq.MQCBCT_EVENT_CALL || mqret.MQRC == ibmmq.MQRC_NONE {
+		return
+	}
+
+	rcInt := int(mqret.MQRC)
+	errCode := strconv.Itoa(rcInt)
+	reason := ibmmq.MQItoString("RC", rcInt)
+
+	ce.mutex.Lock()
+
+	// Keep track of whether the client is currently reconnecting, so that we
+	// can enforce the reconnect timeout that is configured by the application.
+	switch mqret.MQRC {
+	case ibmmq.MQRC_RECONNECTING:
+		if ce.reconnectTimer == nil && !ce.closed {
+			timeoutSecs := ce.cf.ClientReconnectTimeout
+			if timeoutSecs <= 0 {
+				timeoutSecs = ClientReconnectTimeout_DEFAULT
+			}
+			timeout := time.Duration(timeoutSecs) * time.Second
+			ce.reconnectTimer = time.AfterFunc(timeout, ce.reconnectTimedOut)
+		}
+	case ibmmq.MQRC_RECONNECTED:
+		if ce.reconnectTimer != nil {
+			ce.reconnectTimer.Stop()
+			ce.reconnectTimer = nil
+		}
+
+		// The connection can be used again if the client reconnected after
+		// our timeout expired, but not once the client has reported that it
+		// has given up.
+		if ce.reconnectErr != nil && ce.reconnectErr.GetErrorCode() == strconv.Itoa(int(ibmmq.MQRC_RECONNECT_TIMED_OUT)) {
+			ce.reconnectErr = nil
+		}
+	case ibmmq.MQRC_RECONNECT_FAILED:
+		ce.reconnectErr = jms20subset.CreateJMSException(reason, errCode, mqret)
+	}
+
+	ce.mutex.Unlock()
+
+	ce.publish(jms20subset.CreateJMSException(reason, errCode, mqret))
+}
+
+// reconnectTimedOut is called if the client has not managed to reconnect to
+// a queue manager within the reconnect timeout. From then on the connection
+// is treated as failed, so that applications find out straight away rather
+// than waiting for the MQ client to give up. MQ calls that are already
+// blocked waiting for the reconnection are not affected, and if the client
+// does reconnect later on then the connection can be used again.
+func (ce *connectionEvents) reconnectTimedOut() {
+
+	rcInt := int(ibmmq.MQRC_RECONNECT_TIMED_OUT)
+	errCode := strconv.Itoa(rcInt)
+	reason := ibmmq.MQItoString("RC", rcInt)
+	ex := jms20subset.CreateJMSException(reason, errCode, nil)
+
+	ce.mutex.Lock()
+	if ce.reconnectTimer == nil {
+		// The client reconnected just as the timer expired.
+		ce.mutex.Unlock()
+		return
+	}
+	ce.reconnectTimer = nil
+	ce.reconnectErr = ex
+	ce.mutex.Unlock()
+
+	ce.publish(ex)
+}
+
+// checkReconnectState returns an error if the client failed to reconnect to
+// a queue manager after the connection was broken, or nil otherwise.
+func (ce *connectionEvents) checkReconnectState() jms20subset.JMSException {
+
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
+
+	return ce.reconnectErr
+}
+
+// publish queues an event to be passed to the exception listener, unless
+// the connection handle has been closed in the meantime.
+func (ce *connectionEvents) publish(ex jms20subset.JMSException) {
+
+	ce.mutex.Lock()
+	events := ce.events
+	ce.mutex.Unlock()
+
+	if events == nil {
+		return
+	}
+
+	select {
+	case events <- ex:
+	case <-ce.done:
+	}
+}
+
+// dispatch passes each event to the exception listeners that are registered
+// at the time the event is delivered, until the connection handle is closed.
+func (ce *connectionEvents) dispatch() {
+
+	for {
+		select {
+		case ex := <-ce.events:
+
+			ce.mutex.Lock()
+			listeners := make([]func(jms20subset.JMSException), 0, len(ce.listeners))
+			for _, listener := range ce.listeners {
+				listeners = append(listeners, listener)
+			}
+			ce.mutex.Unlock()
+
+			for _, listener := range listeners {
+				listener(ex)
+			}
+
+		case <-ce.done:
+			return
+		}
+	}
+}
@@ -24,3 +24,92 @@ const TLSClientAuth_NONE string = "NONE"
 // Used to configure the TLSClientAuth property to indicate that a client
 // certificate must be sent to the queue manager, as part of mutual TLS.
 const TLSClientAuth_REQUIRED string = "REQUIRED"
+
+// The smallest non-zero value of the TLSKeyResetCount property of the
+// ConnectionFactory.
+const TLSKeyResetCount_MIN int = 32768
+
+// The largest value of the TLSKeyResetCount property of the ConnectionFactory.
+const TLSKeyResetCount_MAX int = 999999999
+
+// Used to configure the KeepAliveInterval property of the ConnectionFactory,
+// so that the interval between TCP keepalives is determined by the
+// negotiated heartbeat interval. This is the default.
+const KeepAliveInterval_AUTO int = -1
+
+// Used to configure the SharingConversations property of the ConnectionFactory,
+// so that each connection uses its own TCP socket and the channel behaves in
+// the same way as for MQ versions before 7.0 (equivalent to SHARECNV(0)).
+const SharingConversations_NONE int = -1
+
+// Used to configure the ClientReconnectOptions property of the
+// ConnectionFactory, so that automatic client reconnection is controlled by the
+// DEFRECON attribute of the channel or the mqclient.ini file. This is the default.
+const ClientReconnect_AS_DEF int = 0
+
+// Used to configure the ClientReconnectOptions property of the
+// ConnectionFactory, so that the application is not reconnected automatically
+// if the connection to the queue manager is broken.
+const ClientReconnect_DISABLED int = 1
+
+// Used to configure the ClientReconnectOptions property of the
+// ConnectionFactory, so that the application is reconnected automatically to
+// any of the queue managers in the connection name list if the connection is
+// broken.
+const ClientReconnect_RECONNECT int = 2
+
+// Used to configure the ClientReconnectOptions property of the
+// ConnectionFactory, so that the application is reconnected automatically only
+// to the same queue manager that it was originally connected to.
+const ClientReconnect_QMGR int = 3
+
+// The default number of seconds that the client attempts to reconnect to a
+// queue manager before giving up, if no ClientReconnectTimeout is specified.
+const ClientReconnectTimeout_DEFAULT int = 1800
+
+// The error code of the JMSException that is returned by CreateContext if the
+// hostname of the queue manager cannot be resolved within the ConnectTimeout.
+const ErrorCode_UNKNOWN_HOST string = "UnknownHost"
+
+// The error code of the JMSException that is returned by CreateContext if
+// nothing is listening on the host and port of the queue manager.
+const ErrorCode_CONNECTION_REFUSED string = "ConnectionRefused"
+
+// The error code of the JMSException that is returned by CreateContext if the
+// connection to the queue manager is not established within the ConnectTimeout.
+const ErrorCode_CONNECT_TIMEOUT string = "ConnectTimeout"
+
+// The error code of the JMSException that is returned by CreateContext if the
+// CredentialsProvider of the ConnectionFactory returns an error.
+const ErrorCode_CREDENTIALS_UNAVAILABLE string = "CredentialsUnavailable"
+
+// The error code of the JMSException that is returned by CreateContext if the
+// host of the queue manager cannot be reached for any other reason, such as
+// there being no route to the host.
+const ErrorCode_HOST_UNREACHABLE string = "HostUnreachable"
+
+// The error code of the JMSException that is returned by the CreateContext
+// method of a PooledConnectionFactory if the maximum number of active contexts
+// are already in use, and none is returned to the pool within the MaxWait time.
+const ErrorCode_POOL_EXHAUSTED string = "PoolExhausted"
+
+// The default number of idle contexts that are kept by a PooledConnectionFactory,
+// if no MaxIdle is specified.
+const PoolMaxIdle_DEFAULT int = 8
+
+// The default number of queues that each context keeps open for sending
+// messages, if no HandleCacheSize is specified on the ConnectionFactory.
+const HandleCacheSize_DEFAULT int = 16
+
+// Disables the caching of queue handles, so that the queue is opened and
+// closed each time that a message is sent.
+const HandleCacheSize_DISABLED int = -1
+
+// The default maximum number of milliseconds between the checks that a
+// DelayScheduler makes for messages that are due, if no PollInterval is
+// specified.
+const DelaySchedulerPollInterval_DEFAULT int = 5000
+
+// The default number of times that a DelayScheduler tries to deliver a
+// message before giving up on it, if no MaxDeliveryAttempts is specified.
+const DelaySchedulerMaxDeliveryAttempts_DEFAULT int = 5
@@ -15,19 +15,29 @@ import (
 	"github.com/ibm-messaging/mq-golang/ibmmq"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // ConsumerImpl defines a struct that contains the necessary objects for
 // receiving messages from a queue on an IBM MQ queue manager.
 type ConsumerImpl struct {
+	ctx      *ContextImpl
 	qObject  ibmmq.MQObject
 	selector string
+	closed   bool // Protected by the mutex of the parent context
+
+	// The message handle that receives the properties of each message, which
+	// is created by the first receive and reused until the consumer is closed.
+	// The mutex is held while the handle is in use.
+	msgHandleMutex sync.Mutex
+	msgHandle      ibmmq.MQMessageHandle
+	hasMsgHandle   bool
 }
 
 // ReceiveNoWait implements the IBM MQ logic necessary to receive a message from
 // a Destination, or immediately return a nil Message if there is no available
 // message to be received.
-func (consumer ConsumerImpl) ReceiveNoWait() (jms20subset.Message, jms20subset.JMSException) {
+func (consumer *ConsumerImpl) ReceiveNoWait() (jms20subset.Message, jms20subset.JMSException) {
 
 	gmo := ibmmq.NewMQGMO()
 	return consumer.receiveInternal(gmo)
@@ -37,7 +47,7 @@ func (consumer ConsumerImpl) ReceiveNoWait() (jms20subset.Message, jms20subset.J
 // Receive(waitMillis) returns a message if one is available, or otherwise
 // waits for up to the specified number of milliseconds for one to become
 // available. A value of zero or less indicates to wait indefinitely.
-func (consumer ConsumerImpl) Receive(waitMillis int32) (jms20subset.Message, jms20subset.JMSException) {
+func (consumer *ConsumerImpl) Receive(waitMillis int32) (jms20subset.Message, jms20subset.JMSException) {
 
 	if waitMillis <= 0 {
 		waitMillis = ibmmq.MQWI_UNLIMITED
@@ -53,12 +63,20 @@ func (consumer ConsumerImpl) Receive(waitMillis int32) (jms20subset.Message, jms
 
 // Internal method to provide common functionality across the different types
 // of receive.
-func (consumer ConsumerImpl) receiveInternal(gmo *ibmmq.MQGMO) (jms20subset.Message, jms20subset.JMSException) {
+func (consumer *ConsumerImpl) receiveInternal(gmo *ibmmq.MQGMO) (jms20subset.Message, jms20subset.JMSException) {
 
 	// Prepare objects to be used in receiving the message.
 	var msg jms20subset.Message
 	var jmsErr jms20subset.JMSException
 
+	if consumer.isClosed() {
+		return nil, createIllegalStateException("JMSConsumer")
+	}
+
+	if connErr := consumer.ctx.conn.checkState(); connErr != nil {
+		return nil, connErr
+	}
+
 	getmqmd := ibmmq.NewMQMD()
 	buffer := make([]byte, 32768)
 
@@ -73,8 +91,43 @@ func (consumer ConsumerImpl) receiveInternal(gmo *ibmmq.MQGMO) (jms20subset.Mess
 		return nil, jmsErr
 	}
 
-	// Use the prepared objects to ask for a message from the queue.
-	datalen, err := consumer.qObject.Get(getmqmd, gmo, buffer)
+	// Only one receive can use the message handle at a time, which does not
+	// cost any concurrency as the calls are serialised by the MQ client.
+	consumer.msgHandleMutex.Lock()
+	defer consumer.msgHandleMutex.Unlock()
+
+	if consumer.isClosed() {
+		return nil, createIllegalStateException("JMSConsumer")
+	}
+
+	// Ask for the message properties to be returned in a message handle, so
+	// that they are not included in the body of the message. Each get replaces
+	// the properties that the handle holds from the previous message.
+	if !consumer.hasMsgHandle {
+		consumer.msgHandle, err = consumer.ctx.qMgr.CrtMH(ibmmq.NewMQCMHO())
+		consumer.hasMsgHandle = err == nil
+	}
+
+	if err == nil {
+		gmo.Options |= ibmmq.MQGMO_PROPERTIES_IN_HANDLE
+		gmo.MsgHandle = consumer.msgHandle
+
+		// Use the prepared objects to ask for a message from the queue.
+		var datalen int
+		datalen, err = consumer.qObject.Get(getmqmd, gmo, buffer)
+
+		// The data length is the real length of the message if it was too big
+		// for the buffer, so only use it once the message has been received.
+		if err == nil {
+			buffer = buffer[:datalen]
+		}
+	}
+
+	var properties map[string]interface{}
+	var headers map[string]interface{}
+	if err == nil {
+		properties, headers, err = readPropertiesHandle(consumer.msgHandle)
+	}
 
 	if err == nil {
 
@@ -83,15 +136,20 @@ func (consumer ConsumerImpl) receiveInternal(gmo *ibmmq.MQGMO) (jms20subset.Mess
 		// message and populate it into a text string.
 		var msgBodyStr *string
 
-		if datalen > 0 {
-			strContent := strings.TrimSpace(string(buffer[:datalen]))
+		if len(buffer) > 0 {
+			strContent := strings.TrimSpace(string(buffer))
 			msgBodyStr = &strContent
 		}
 
-		msg = &TextMessageImpl{
+		textMsg := &TextMessageImpl{
 			bodyStr: msgBodyStr,
 			mqmd:    getmqmd,
 		}
+		textMsg.setHeaders(headers)
+		if len(properties) > 0 {
+			textMsg.properties = properties
+		}
+		msg = textMsg
 
 	} else {
 
@@ -108,6 +166,7 @@ func (consumer ConsumerImpl) receiveInternal(gmo *ibmmq.MQGMO) (jms20subset.Mess
 
 			// Parse the details of the error and return it to the caller as
 			// a JMSException
+			consumer.ctx.conn.noteError(err)
 			rcInt := int(mqret.MQRC)
 			errCode := strconv.Itoa(rcInt)
 			reason := ibmmq.MQItoString("RC", rcInt)
@@ -124,7 +183,7 @@ func (consumer ConsumerImpl) receiveInternal(gmo *ibmmq.MQGMO) (jms20subset.Mess
 // message from a Destination and return its body as a string.
 //
 // If no message is immediately available to be returned then a nil is returned.
-func (consumer ConsumerImpl) ReceiveStringBodyNoWait() (*string, jms20subset.JMSException) {
+func (consumer *ConsumerImpl) ReceiveStringBodyNoWait() (*string, jms20subset.JMSException) {
 
 	var msgBodyStrPtr *string
 	var jmsErr jms20subset.JMSException
@@ -154,7 +213,7 @@ func (consumer ConsumerImpl) ReceiveStringBodyNoWait() (*string, jms20subset.JMS
 //
 // If no message is available the method blocks up to the specified number
 // of milliseconds for one to become available.
-func (consumer ConsumerImpl) ReceiveStringBody(waitMillis int32) (*string, jms20subset.JMSException) {
+func (consumer *ConsumerImpl) ReceiveStringBody(waitMillis int32) (*string, jms20subset.JMSException) {
 
 	var msgBodyStrPtr *string
 	var jmsErr jms20subset.JMSException
@@ -229,11 +288,48 @@ func applySelector(selector string, getmqmd *ibmmq.MQMD, gmo *ibmmq.MQGMO) error
 
 // Closes the JMSConsumer, releasing any resources that were allocated on
 // behalf of that consumer.
-func (consumer ConsumerImpl) Close() {
+func (consumer *ConsumerImpl) Close() {
+
+	// The consumer no longer needs to be closed when the context is closed.
+	consumer.ctx.removeConsumer(consumer)
+	consumer.closeInternal()
+
+	return
+}
+
+// closeInternal releases the queue handle used by this consumer. It is called
+// either when the application closes the consumer, or when the context that
+// created it is closed, and only has an effect the first time it is called.
+func (consumer *ConsumerImpl) closeInternal() {
+
+	consumer.ctx.mutex.Lock()
+	alreadyClosed := consumer.closed
+	consumer.closed = true
+	consumer.ctx.mutex.Unlock()
+
+	if alreadyClosed {
+		return
+	}
 
 	if (ibmmq.MQObject{}) != consumer.qObject {
 		consumer.qObject.Close(0)
 	}
 
-	return
+	// Wait for any receive that is still using the message handle.
+	consumer.msgHandleMutex.Lock()
+	if consumer.hasMsgHandle {
+		consumer.msgHandle.DltMH(ibmmq.NewMQDMHO())
+		consumer.hasMsgHandle = false
+	}
+	consumer.msgHandleMutex.Unlock()
+}
+
+// isClosed returns true if this consumer, or the context that created it, has
+// been closed.
+func (consumer *ConsumerImpl) isClosed() bool {
+
+	consumer.ctx.mutex.Lock()
+	defer consumer.ctx.mutex.Unlock()
+
+	return consumer.closed || consumer.ctx.closed
 }
@@ -13,32 +13,100 @@ import (
 	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
 	"github.com/ibm-messaging/mq-golang/ibmmq"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 // ContextImpl encapsulates the objects necessary to maintain an active
 // connection to an IBM MQ queue manager.
+//
+// A ContextImpl is safe for concurrent use by multiple goroutines. Calls that
+// use the underlying connection are serialised by the MQ client, so for
+// example a Receive that is waiting for a message will delay a Send on the
+// same context, or on another context that shares its connection, until the
+// Receive returns. Applications that need to send and receive in parallel
+// should create a separate context for each goroutine using the
+// ConnectionFactory.
+//
+// The context keeps track of the consumers that it has created so that they
+// can be closed automatically when the context itself is closed. Producers
+// are not tracked (JMSProducer has no Close method) and instead check the state
+// of the context each time they are used. The queues that producers send to
+// are kept open by the context, up to the HandleCacheSize of the
+// ConnectionFactory, and closed along with the context.
 type ContextImpl struct {
-	qMgr ibmmq.MQQueueManager
+	conn        *connectionImpl
+	qMgr        ibmmq.MQQueueManager
+	sessionMode int
+	handles     *handleCache
+
+	// Protects the closed flag and the list of child objects below, as well
+	// as the queue manager name once it has been found out.
+	mutex     sync.Mutex
+	closed    bool
+	consumers []*ConsumerImpl
+	qMgrName  string
 }
 
-// CreateQueue implements the logic necessary to create a provider-specific
-// object representing an IBM MQ queue.
-func (ctx ContextImpl) CreateQueue(queueName string) jms20subset.Queue {
+// newContext creates a context that uses the given connection.
+func newContext(conn *connectionImpl, sessionMode int) *ContextImpl {
+	return &ContextImpl{
+		conn:        conn,
+		qMgr:        conn.qMgr,
+		sessionMode: sessionMode,
+		handles:     newHandleCache(conn.cf.getHandleCacheSize()),
+	}
+}
 
-	// Store the name of the queue
-	queue := QueueImpl{
-		queueName: queueName,
+// CreateContext creates a new context that shares the connection to the
+// queue manager of this context, so no further connection or authentication
+// is needed.
+//
+// The contexts use the same connection handle, so calls made using one of
+// them wait for any call in progress on another. The connection is
+// disconnected when the last of the contexts is closed, and each context can
+// be closed in any order.
+func (ctx *ContextImpl) CreateContext(sessionMode int) (jms20subset.JMSContext, jms20subset.JMSException) {
+
+	// Local transactions and client acknowledgement are not currently
+	// supported, so only accept the session modes that are equivalent to the
+	// way messages are received today.
+	if sessionMode != jms20subset.JMSContext_AUTO_ACKNOWLEDGE &&
+		sessionMode != jms20subset.JMSContext_DUPS_OK_ACKNOWLEDGE {
+		return nil, jms20subset.CreateJMSException(
+			"Unsupported session mode: "+strconv.Itoa(sessionMode), "UnsupportedSessionMode", nil)
+	}
+
+	if ctx.isClosed() || !ctx.conn.addContext() {
+		return nil, createIllegalStateException("JMSContext")
 	}
 
-	return queue
+	return newContext(ctx.conn, sessionMode), nil
+}
+
+// GetSessionMode returns the session mode of this context.
+func (ctx *ContextImpl) GetSessionMode() int {
+	return ctx.sessionMode
+}
+
+// CreateQueue implements the logic necessary to create a provider-specific
+// object representing an IBM MQ queue.
+//
+// The queue name can be in the URI form "queue://QMName/QueueName" to send
+// messages to a queue on another queue manager, which are routed to it by the
+// queue manager that the application is connected to (for example using a
+// transmission queue with the same name as the remote queue manager).
+func (ctx *ContextImpl) CreateQueue(queueName string) jms20subset.Queue {
+	return parseQueueName(queueName)
 }
 
 // CreateProducer implements the logic necessary to create a JMSProducer object
 // that allows messages to be sent to destinations in IBM MQ.
-func (ctx ContextImpl) CreateProducer() jms20subset.JMSProducer {
+func (ctx *ContextImpl) CreateProducer() jms20subset.JMSProducer {
 
 	// Initialise the Producer with the attributes necessary for it to send
-	// messages.
+	// messages. Note that if this context has already been closed then any
+	// attempt to send a message using the producer will return an error.
 	producer := ProducerImpl{
 		ctx:          ctx,
 		deliveryMode: jms20subset.DeliveryMode_PERSISTENT,
@@ -47,15 +115,32 @@ func (ctx ContextImpl) CreateProducer() jms20subset.JMSProducer {
 	return &producer
 }
 
+// CreateProducerForDestination creates a JMSProducer object that sends
+// messages to the specified destination in IBM MQ.
+func (ctx *ContextImpl) CreateProducerForDestination(dest jms20subset.Destination) jms20subset.JMSProducer {
+
+	producer := ProducerImpl{
+		ctx:          ctx,
+		dest:         dest,
+		deliveryMode: jms20subset.DeliveryMode_PERSISTENT,
+	}
+
+	return &producer
+}
+
 // CreateConsumer creates a consumer object that allows an application to
 // receive messages from the specified Destination.
-func (ctx ContextImpl) CreateConsumer(dest jms20subset.Destination) (jms20subset.JMSConsumer, jms20subset.JMSException) {
+func (ctx *ContextImpl) CreateConsumer(dest jms20subset.Destination) (jms20subset.JMSConsumer, jms20subset.JMSException) {
 	return ctx.CreateConsumerWithSelector(dest, "")
 }
 
 // CreateConsumer creates a consumer object that allows an application to
 // receive messages that match the specified selector from the given Destination.
-func (ctx ContextImpl) CreateConsumerWithSelector(dest jms20subset.Destination, selector string) (jms20subset.JMSConsumer, jms20subset.JMSException) {
+func (ctx *ContextImpl) CreateConsumerWithSelector(dest jms20subset.Destination, selector string) (jms20subset.JMSConsumer, jms20subset.JMSException) {
+
+	if stateErr := ctx.checkState(); stateErr != nil {
+		return nil, stateErr
+	}
 
 	// First validate the selector string format (we don't make use of it at
 	// runtime until the receive is called)
@@ -70,12 +155,10 @@ func (ctx ContextImpl) CreateConsumerWithSelector(dest jms20subset.Destination,
 	}
 
 	// Set up the necessary objects to open the queue
-	mqod := ibmmq.NewMQOD()
+	mqod := toQueueImpl(dest).openDescriptor()
 	var openOptions int32
 	openOptions = ibmmq.MQOO_FAIL_IF_QUIESCING
 	openOptions |= ibmmq.MQOO_INPUT_AS_Q_DEF
-	mqod.ObjectType = ibmmq.MQOT_Q
-	mqod.ObjectName = dest.GetDestinationName()
 
 	var retErr jms20subset.JMSException
 	var consumer jms20subset.JMSConsumer
@@ -87,14 +170,29 @@ func (ctx ContextImpl) CreateConsumerWithSelector(dest jms20subset.Destination,
 
 		// Success - store the necessary objects away for later use to receive
 		// messages.
-		consumer = ConsumerImpl{
+		consumerImpl := &ConsumerImpl{
+			ctx:      ctx,
 			qObject:  qObject,
 			selector: selector,
 		}
 
+		// Register the consumer so that it is closed along with this context,
+		// unless the context was closed while we were opening the queue.
+		ctx.mutex.Lock()
+		if ctx.closed {
+			ctx.mutex.Unlock()
+			consumerImpl.closeInternal()
+			return nil, createIllegalStateException("JMSContext")
+		}
+		ctx.consumers = append(ctx.consumers, consumerImpl)
+		ctx.mutex.Unlock()
+
+		consumer = consumerImpl
+
 	} else {
 
 		// Error occurred - extract the failure details and return to the caller.
+		ctx.conn.noteError(err)
 		rcInt := int(err.(*ibmmq.MQReturn).MQRC)
 		errCode := strconv.Itoa(rcInt)
 		reason := ibmmq.MQItoString("RC", rcInt)
@@ -106,24 +204,201 @@ func (ctx ContextImpl) CreateConsumerWithSelector(dest jms20subset.Destination,
 }
 
 // CreateTextMessage is a JMS standard mechanism for creating a TextMessage.
-func (ctx ContextImpl) CreateTextMessage() jms20subset.TextMessage {
+func (ctx *ContextImpl) CreateTextMessage() jms20subset.TextMessage {
 	return &TextMessageImpl{}
 }
 
 // CreateTextMessage is a JMS standard mechanism for creating a TextMessage
 // and initialise it with the chosen text string.
-func (ctx ContextImpl) CreateTextMessageWithString(txt string) jms20subset.TextMessage {
+func (ctx *ContextImpl) CreateTextMessageWithString(txt string) jms20subset.TextMessage {
 	return &TextMessageImpl{
 		bodyStr: &txt,
 	}
 }
 
+// SetExceptionListener registers a function that is called when the MQ event
+// handler reports an event that affects the connection used by this context,
+// such as MQRC_CONNECTION_BROKEN, MQRC_Q_MGR_QUIESCING or MQRC_RECONNECTING.
+// Each context has its own listener, so setting a listener on a context
+// created using CreateContext does not replace the listener of the context
+// that it was created from, and both are told about events on the connection
+// that they share.
+//
+// The listener is called on a separate goroutine, and the JMSException that
+// it receives contains the MQ reason code of the event.
+func (ctx *ContextImpl) SetExceptionListener(listener func(jms20subset.JMSException)) jms20subset.JMSException {
+
+	if ctx.isClosed() {
+		return createIllegalStateException("JMSContext")
+	}
+
+	return ctx.conn.events.setListener(ctx, listener)
+}
+
 // Close this connection to the MQ queue manager, and release any resources
 // that were allocated to support this connection.
-func (ctx ContextImpl) Close() {
+//
+// Any consumers that were created from this context are closed first, so that
+// their queue handles are released before the connection to the queue manager
+// is disconnected. Producers created from this context can no longer be used
+// to send messages once it has been closed. Calling Close on a context that has
+// already been closed has no effect.
+func (ctx *ContextImpl) Close() {
+
+	// Mark the context as closed and take ownership of the child objects, so
+	// that no new objects can be registered while we are closing them.
+	ctx.mutex.Lock()
+	if ctx.closed {
+		ctx.mutex.Unlock()
+		return
+	}
+	ctx.closed = true
+	consumers := ctx.consumers
+	ctx.consumers = nil
+	ctx.mutex.Unlock()
+
+	for _, consumer := range consumers {
+		consumer.closeInternal()
+	}
+
+	// Report the outcome of any asynchronous sends while the connection can
+	// still be used to find it out.
+	ctx.conn.asyncSends.check()
+
+	ctx.handles.close()
+	ctx.conn.events.setListener(ctx, nil)
+
+	// The connection is disconnected when the last context using it is closed.
+	ctx.conn.removeContext()
+
+}
 
-	if (ibmmq.MQQueueManager{}) != ctx.qMgr {
-		ctx.qMgr.Disc()
+// checkState returns an error if this context can no longer be used, either
+// because it has been closed or because its connection is broken and could
+// not be reconnected.
+func (ctx *ContextImpl) checkState() jms20subset.JMSException {
+
+	if ctx.isClosed() {
+		return createIllegalStateException("JMSContext")
 	}
 
+	return ctx.conn.checkState()
+}
+
+// ping checks that the connection used by this context is still working, by
+// making a call to the queue manager that does not have any side effects.
+func (ctx *ContextImpl) ping() jms20subset.JMSException {
+
+	if stateErr := ctx.checkState(); stateErr != nil {
+		return stateErr
+	}
+
+	mqod := ibmmq.NewMQOD()
+	mqod.ObjectType = ibmmq.MQOT_Q_MGR
+
+	qMgrObject, err := ctx.qMgr.Open(mqod, ibmmq.MQOO_INQUIRE|ibmmq.MQOO_FAIL_IF_QUIESCING)
+	if err == nil {
+		err = qMgrObject.Close(0)
+	}
+
+	if err != nil {
+		ctx.conn.noteError(err)
+		rcInt := int(err.(*ibmmq.MQReturn).MQRC)
+		errCode := strconv.Itoa(rcInt)
+		reason := ibmmq.MQItoString("RC", rcInt)
+		return jms20subset.CreateJMSException(reason, errCode, err)
+	}
+
+	return nil
+}
+
+// queueManagerName returns the name of the queue manager that this context is
+// connected to, which is asked for the first time that it is needed because
+// the ConnectionFactory may not name it. An empty string is returned if the
+// name could not be found out.
+func (ctx *ContextImpl) queueManagerName() string {
+
+	ctx.mutex.Lock()
+	qMgrName := ctx.qMgrName
+	ctx.mutex.Unlock()
+
+	if qMgrName != "" {
+		return qMgrName
+	}
+
+	mqod := ibmmq.NewMQOD()
+	mqod.ObjectType = ibmmq.MQOT_Q_MGR
+
+	qMgrObject, err := ctx.qMgr.Open(mqod, ibmmq.MQOO_INQUIRE)
+	if err != nil {
+		return ""
+	}
+	defer qMgrObject.Close(0)
+
+	attrs, err := qMgrObject.Inq([]int32{ibmmq.MQCA_Q_MGR_NAME})
+	if err != nil {
+		return ""
+	}
+
+	qMgrName, _ = attrs[ibmmq.MQCA_Q_MGR_NAME].(string)
+	qMgrName = strings.TrimSpace(qMgrName)
+
+	ctx.mutex.Lock()
+	ctx.qMgrName = qMgrName
+	ctx.mutex.Unlock()
+
+	return qMgrName
+}
+
+// isSameQueue returns true if the two queues are the same queue, where a
+// queue without a queue manager name is on the queue manager that this context
+// is connected to.
+func (ctx *ContextImpl) isSameQueue(queue1 QueueImpl, queue2 QueueImpl) bool {
+
+	if queue1.queueName != queue2.queueName {
+		return false
+	}
+
+	if queue1.queueManagerName == queue2.queueManagerName {
+		return true
+	}
+
+	// Only one of them can be empty, so the other has to be the name of the
+	// queue manager that we are connected to.
+	localName := ctx.queueManagerName()
+	return localName != "" &&
+		(queue1.queueManagerName == "" || queue1.queueManagerName == localName) &&
+		(queue2.queueManagerName == "" || queue2.queueManagerName == localName)
+}
+
+// isClosed returns true if the Close method has been called on this context.
+func (ctx *ContextImpl) isClosed() bool {
+
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	return ctx.closed
+}
+
+// removeConsumer deregisters a consumer that has been closed by the
+// application so that it is not closed a second time with the context.
+func (ctx *ContextImpl) removeConsumer(consumer *ConsumerImpl) {
+
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	for i, c := range ctx.consumers {
+		if c == consumer {
+			ctx.consumers = append(ctx.consumers[:i], ctx.consumers[i+1:]...)
+			break
+		}
+	}
+}
+
+// createIllegalStateException returns the error that is given to an
+// application that attempts to use an object after it has been closed, which
+// is equivalent to the IllegalStateException in Java JMS.
+func createIllegalStateException(objectType string) jms20subset.JMSException {
+	return jms20subset.CreateJMSException(objectType+" has been closed",
+		"IllegalStateException", nil)
 }
@@ -0,0 +1,104 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// CredentialsProvider is implemented by applications that need to supply the
+// credentials for a connection at the time that it is made, for example
+// because they are held by a secrets manager that rotates them regularly.
+//
+// Credentials is called each time that CreateContext connects to the queue
+// manager, and is passed a context that expires at the ConnectTimeout of the
+// ConnectionFactory if one has been set. Contexts created using
+// JMSContext.CreateContext share the existing connection, so it is not called
+// for them.
+//
+// The automatic client reconnection of the MQ client always reuses the
+// credentials that were supplied when the connection was first made, so the
+// ClientReconnectOptions of a ConnectionFactory that has a CredentialsProvider
+// must be ClientReconnect_DISABLED. Instead an application reconnects by
+// creating a new context when the connection is broken, for which the
+// provider is called again. A PooledConnectionFactory creates a new context in
+// place of one whose connection was found to be broken by an earlier call, or
+// by the check made when TestOnBorrow is set.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (user string, secret string, err error)
+}
+
+// FileCredentialsProvider reads the user name and password from files each
+// time that a connection is made, which suits secrets that are mounted into a
+// container as files, such as a Kubernetes secret. Any whitespace at the start
+// or end of the files (such as a trailing newline) is ignored.
+type FileCredentialsProvider struct {
+	UserNameFile string
+	PasswordFile string
+}
+
+// Credentials reads the current user name and password from the files.
+func (provider FileCredentialsProvider) Credentials(ctx context.Context) (user string, secret string, err error) {
+
+	user, err = readCredentialsFile(provider.UserNameFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, err = readCredentialsFile(provider.PasswordFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	return user, secret, nil
+}
+
+// readCredentialsFile returns the trimmed content of a file that contains a
+// single credential.
+func readCredentialsFile(fileName string) (string, error) {
+
+	if fileName == "" {
+		return "", errors.New("No credentials file has been specified")
+	}
+
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// EnvCredentialsProvider reads the user name and password from the named
+// environment variables each time that a connection is made.
+type EnvCredentialsProvider struct {
+	UserNameVar string
+	PasswordVar string
+}
+
+// Credentials reads the current user name and password from the environment.
+func (provider EnvCredentialsProvider) Credentials(ctx context.Context) (user string, secret string, err error) {
+
+	user, found := os.LookupEnv(provider.UserNameVar)
+	if !found {
+		return "", "", errors.New("Environment variable " + provider.UserNameVar + " is not set")
+	}
+
+	secret, found = os.LookupEnv(provider.PasswordVar)
+	if !found {
+		return "", "", errors.New("Environment variable " + provider.PasswordVar + " is not set")
+	}
+
+	return user, secret, nil
+}
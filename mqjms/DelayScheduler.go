@@ -0,0 +1,506 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"encoding/hex"
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang/ibmmq"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DelayScheduler delivers the messages that were sent with a delivery delay.
+// Those messages are held on the DeliveryDelayQueue of the Factory, and the
+// scheduler moves each one to its destination once its delivery time has
+// passed.
+//
+// The scheduler keeps no state of its own, as the delivery time and
+// destination are held as properties of each message on the staging queue.
+// So the scheduler can be stopped and started again (or run in a different
+// process) without losing any messages, and several schedulers can share the
+// same staging queue.
+//
+// Each message is delivered using the message descriptor that it was sent
+// with, so that its message ID, correlation ID, reply destination,
+// persistence, priority, timestamp and user ID are unchanged, along with its
+// properties. This uses MQPMO_SET_ALL_CONTEXT, so the user that the scheduler
+// connects as needs setall authority on the destination queues.
+//
+// A message that cannot be put to its destination is left on the staging
+// queue and tried again later, up to MaxDeliveryAttempts times (counted using
+// the BackoutCount of the message, which is only kept across a queue manager
+// restart if the staging queue has HARDENBO set). After that it is moved to
+// the DeadLetterQueue with a dead-letter header, or if there is no
+// DeadLetterQueue (or the message cannot be put to it) it is left where it
+// is and reported once. Each later check of the staging queue browses past it
+// without getting it again. A message that has been left is tried and
+// reported again when the scheduler reconnects to the queue manager, or on the
+// next call to DeliverDue.
+//
+// The scheduler can run in the background of an application;
+//
+//	scheduler := &mqjms.DelayScheduler{Factory: cf}
+//	err := scheduler.Start()
+//	...
+//	scheduler.Stop()
+//
+// or DeliverDue can be called periodically, for example by a command that is
+// run on a schedule.
+type DelayScheduler struct {
+	Factory ConnectionFactoryImpl
+
+	// The maximum number of milliseconds between checks for messages that
+	// are due, which is how long it takes at most for a newly sent message to
+	// be noticed. Defaults to DelaySchedulerPollInterval_DEFAULT.
+	PollInterval int
+
+	// The number of times that delivering a message is tried before it is
+	// given up on. Defaults to DelaySchedulerMaxDeliveryAttempts_DEFAULT.
+	MaxDeliveryAttempts int
+
+	// The queue that messages which cannot be delivered are moved to, such as
+	// the dead-letter queue of the queue manager. Optional.
+	DeadLetterQueue string
+
+	// Called with each problem found by a scheduler that is running in the
+	// background, such as a message that could not be delivered or a failure
+	// of the connection to the queue manager.
+	ExceptionListener func(jms20subset.JMSException)
+
+	// Protects the attributes below.
+	mutex sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Start connects to the queue manager and starts delivering messages in the
+// background, until Stop is called. If the connection fails then the
+// scheduler reports the problem and connects again.
+func (scheduler *DelayScheduler) Start() jms20subset.JMSException {
+
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	if scheduler.stop != nil {
+		return jms20subset.CreateJMSException("DelayScheduler has already been started",
+			"IllegalStateException", nil)
+	}
+
+	mover, err := scheduler.connect()
+	if err != nil {
+		return err
+	}
+
+	scheduler.stop = make(chan struct{})
+	scheduler.done = make(chan struct{})
+	go scheduler.run(mover, scheduler.stop, scheduler.done)
+
+	return nil
+}
+
+// Stop stops a scheduler that was started by Start, waiting for it to finish
+// delivering the current message.
+func (scheduler *DelayScheduler) Stop() {
+
+	scheduler.mutex.Lock()
+	stop := scheduler.stop
+	done := scheduler.done
+	scheduler.stop = nil
+	scheduler.done = nil
+	scheduler.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// DeliverDue moves every message on the staging queue whose delivery time has
+// passed to its destination, and returns the number of messages that were
+// delivered. The first problem found is returned, after trying to deliver the
+// rest of the messages that are due.
+func (scheduler *DelayScheduler) DeliverDue() (int, jms20subset.JMSException) {
+
+	mover, err := scheduler.connect()
+	if err != nil {
+		return 0, err
+	}
+	defer mover.close()
+
+	var firstErr jms20subset.JMSException
+	delivered, _, err := mover.deliverDue(func(problem jms20subset.JMSException) {
+		if firstErr == nil {
+			firstErr = problem
+		}
+	})
+
+	if err != nil {
+		return delivered, err
+	}
+
+	return delivered, firstErr
+}
+
+// run delivers messages until the stop channel is closed.
+func (scheduler *DelayScheduler) run(mover *delayMover, stop chan struct{}, done chan struct{}) {
+
+	defer close(done)
+
+	pollInterval := time.Duration(scheduler.PollInterval) * time.Millisecond
+	if scheduler.PollInterval <= 0 {
+		pollInterval = time.Duration(DelaySchedulerPollInterval_DEFAULT) * time.Millisecond
+	}
+
+	for {
+		wait := pollInterval
+
+		var err jms20subset.JMSException
+		if mover == nil {
+			mover, err = scheduler.connect()
+		}
+
+		if mover != nil {
+			var next time.Time
+			_, next, err = mover.deliverDue(scheduler.report)
+
+			if err != nil {
+				mover.close()
+				mover = nil
+
+			} else if !next.IsZero() && time.Until(next) < wait {
+				// Wake up in time for the next message that is due.
+				wait = time.Until(next)
+				if wait < 0 {
+					wait = 0
+				}
+			}
+		}
+
+		if err != nil {
+			scheduler.report(err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			if mover != nil {
+				mover.close()
+			}
+			return
+
+		case <-timer.C:
+		}
+	}
+}
+
+// report passes a problem to the ExceptionListener, if there is one.
+func (scheduler *DelayScheduler) report(err jms20subset.JMSException) {
+	if scheduler.ExceptionListener != nil {
+		scheduler.ExceptionListener(err)
+	}
+}
+
+// connect creates a connection to the queue manager and opens the staging
+// queue.
+func (scheduler *DelayScheduler) connect() (*delayMover, jms20subset.JMSException) {
+
+	if scheduler.Factory.DeliveryDelayQueue == "" {
+		return nil, jms20subset.CreateJMSException("The DeliveryDelayQueue of the ConnectionFactory "+
+			"must be set to use a DelayScheduler", "UnsupportedDeliveryDelay", nil)
+	}
+
+	qMgr, jmsErr := scheduler.Factory.connect()
+	if jmsErr != nil {
+		return nil, jmsErr
+	}
+
+	mqod := ibmmq.NewMQOD()
+	mqod.ObjectType = ibmmq.MQOT_Q
+	mqod.ObjectName = scheduler.Factory.DeliveryDelayQueue
+
+	// The staging queue is browsed to find the messages that are due, which
+	// are then removed using the browse cursor.
+	staging, err := qMgr.Open(mqod, ibmmq.MQOO_BROWSE|ibmmq.MQOO_INPUT_SHARED|ibmmq.MQOO_FAIL_IF_QUIESCING)
+	if err != nil {
+		qMgr.Disc()
+		return nil, createMQException(err)
+	}
+
+	maxAttempts := scheduler.MaxDeliveryAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DelaySchedulerMaxDeliveryAttempts_DEFAULT
+	}
+
+	mover := &delayMover{
+		qMgr:            qMgr,
+		staging:         staging,
+		targets:         make(map[string]ibmmq.MQObject),
+		maxAttempts:     maxAttempts,
+		deadLetterQueue: scheduler.DeadLetterQueue,
+		setAside:        make(map[string]bool),
+	}
+
+	return mover, nil
+}
+
+// delayMover holds the connection and queue handles that a DelayScheduler
+// uses to move messages from the staging queue to their destinations.
+type delayMover struct {
+	qMgr    ibmmq.MQQueueManager
+	staging ibmmq.MQObject
+	targets map[string]ibmmq.MQObject
+
+	maxAttempts     int
+	deadLetterQueue string
+
+	// The MsgIds of the messages that could not be delivered and have been
+	// left on the staging queue, which have already been reported and are not
+	// tried again.
+	setAside map[string]bool
+}
+
+// deliverDue makes one pass through the staging queue, delivering the messages
+// that are due. It returns the number of messages that were delivered and the
+// delivery time of the next message that is not yet due, if there is one.
+//
+// A problem with an individual message is passed to the report function, and
+// an error is only returned if the staging queue can no longer be used.
+func (mover *delayMover) deliverDue(report func(jms20subset.JMSException)) (int, time.Time, jms20subset.JMSException) {
+
+	var next time.Time
+	delivered := 0
+
+	msgHandle, err := mover.qMgr.CrtMH(ibmmq.NewMQCMHO())
+	if err != nil {
+		return 0, next, createMQException(err)
+	}
+	defer msgHandle.DltMH(ibmmq.NewMQDMHO())
+
+	browseOption := ibmmq.MQGMO_BROWSE_FIRST
+	setAside := make(map[string]bool)
+
+	for {
+		// Browse the next message to find out when it is due, without reading
+		// its body.
+		gmo := ibmmq.NewMQGMO()
+		gmo.Options = browseOption | ibmmq.MQGMO_ACCEPT_TRUNCATED_MSG |
+			ibmmq.MQGMO_PROPERTIES_IN_HANDLE | ibmmq.MQGMO_FAIL_IF_QUIESCING
+		gmo.MsgHandle = msgHandle
+		browseOption = ibmmq.MQGMO_BROWSE_NEXT
+
+		browsemd := ibmmq.NewMQMD()
+		datalen, err := mover.staging.Get(browsemd, gmo, nil)
+		if err != nil {
+			switch err.(*ibmmq.MQReturn).MQRC {
+			case ibmmq.MQRC_NO_MSG_AVAILABLE:
+				// Forget about any messages that have been removed from the
+				// staging queue since they were set aside.
+				mover.setAside = setAside
+				return delivered, next, nil
+			case ibmmq.MQRC_TRUNCATED_MSG_ACCEPTED:
+				// Expected, as no buffer was supplied for the body.
+			default:
+				return delivered, next, createMQException(err)
+			}
+		}
+
+		_, headers, err := readPropertiesHandle(msgHandle)
+		if err != nil {
+			return delivered, next, createMQException(err)
+		}
+
+		destName, _ := headers[delayDestinationPropertyName].(string)
+		deliveryTime, timeErr := propertyToInt(deliveryTimePropertyName, headers[deliveryTimePropertyName])
+		if destName == "" || timeErr != nil {
+			// Not a message that was sent with a delivery delay, so leave it
+			// for the application that put it there.
+			continue
+		}
+
+		due := time.Unix(0, int64(deliveryTime)*int64(time.Millisecond))
+		if time.Now().Before(due) {
+			if next.IsZero() || due.Before(next) {
+				next = due
+			}
+			continue
+		}
+
+		// Give up on a message that has failed too many times, so that it
+		// doesn't hold up the scheduler forever.
+		if int(browsemd.BackoutCount) >= mover.maxAttempts {
+			msgId := hex.EncodeToString(browsemd.MsgId)
+			if mover.setAside[msgId] {
+				setAside[msgId] = true
+				continue
+			}
+
+			var problem jms20subset.JMSException
+			if mover.deadLetterQueue != "" {
+				var moved bool
+				moved, problem = mover.moveUnderCursor(msgHandle, destName, datalen, true)
+				if problem == nil {
+					if moved {
+						report(createUndeliverableException(msgId, destName, mover.maxAttempts,
+							"has been moved to "+mover.deadLetterQueue))
+					}
+					continue
+				}
+			}
+
+			// Leave the message where it is, and skip it from now on.
+			if problem != nil {
+				report(problem)
+			}
+			report(createUndeliverableException(msgId, destName, mover.maxAttempts,
+				"has been left on the staging queue"))
+			setAside[msgId] = true
+			continue
+		}
+
+		moved, problem := mover.moveUnderCursor(msgHandle, destName, datalen, false)
+		if problem != nil {
+			report(problem)
+		}
+		if moved {
+			delivered++
+		}
+	}
+}
+
+// createUndeliverableException creates the exception that reports a message
+// that the scheduler has given up trying to deliver.
+func createUndeliverableException(msgId string, destName string, attempts int, outcome string) jms20subset.JMSException {
+
+	rcInt := int(ibmmq.MQRC_BACKOUT_THRESHOLD_REACHED)
+	return jms20subset.CreateJMSException("Message "+msgId+" could not be delivered to "+destName+
+		" after "+strconv.Itoa(attempts)+" attempts and "+outcome, strconv.Itoa(rcInt), nil)
+}
+
+// moveUnderCursor gets the message under the browse cursor and puts it to its
+// destination, or to the dead-letter queue with a dead-letter header if
+// deadLetter is true, in a single unit of work so that the message is never
+// lost or duplicated. It returns false if the message was not moved, including
+// if it was removed by another scheduler in the meantime.
+func (mover *delayMover) moveUnderCursor(msgHandle ibmmq.MQMessageHandle, destName string,
+	datalen int, deadLetter bool) (bool, jms20subset.JMSException) {
+
+	getmqmd := ibmmq.NewMQMD()
+	gmo := ibmmq.NewMQGMO()
+	gmo.Options = ibmmq.MQGMO_MSG_UNDER_CURSOR | ibmmq.MQGMO_SYNCPOINT |
+		ibmmq.MQGMO_PROPERTIES_IN_HANDLE | ibmmq.MQGMO_FAIL_IF_QUIESCING
+	gmo.MsgHandle = msgHandle
+
+	buffer := make([]byte, datalen)
+	datalen, err := mover.staging.Get(getmqmd, gmo, buffer)
+	if err != nil {
+		if err.(*ibmmq.MQReturn).MQRC == ibmmq.MQRC_NO_MSG_UNDER_CURSOR {
+			return false, nil
+		}
+		return false, createMQException(err)
+	}
+
+	// The delivery time is left on the message so that the consumer can read
+	// it, but the destination is only needed on the staging queue.
+	err = msgHandle.DltMP(ibmmq.NewMQDMPO(), delayDestinationPropertyName)
+
+	targetName := destName
+	body := buffer[:datalen]
+	if deadLetter {
+		targetName = mover.deadLetterQueue
+		body = addDeadLetterHeader(getmqmd, destName, body)
+	}
+
+	var target ibmmq.MQObject
+	if err == nil {
+		target, err = mover.openTarget(targetName)
+	}
+
+	if err == nil {
+		pmo := ibmmq.NewMQPMO()
+		pmo.Options = ibmmq.MQPMO_SYNCPOINT | ibmmq.MQPMO_SET_ALL_CONTEXT | ibmmq.MQPMO_FAIL_IF_QUIESCING
+		pmo.OriginalMsgHandle = msgHandle
+
+		err = target.Put(getmqmd, pmo, body)
+		if err != nil {
+			// Open the queue again next time, in case it has been changed.
+			mover.closeTarget(targetName)
+		}
+	}
+
+	if err == nil {
+		err = mover.qMgr.Cmit()
+	}
+
+	if err != nil {
+		mover.qMgr.Back()
+		return false, createMQException(err)
+	}
+
+	return true, nil
+}
+
+// addDeadLetterHeader returns the body of a message with a dead-letter header
+// in front of it that names its destination, and updates the MQMD to match.
+func addDeadLetterHeader(mqmd *ibmmq.MQMD, destName string, body []byte) []byte {
+
+	// The message type is kept, so that the message can be forwarded to its
+	// destination unchanged by a dead-letter queue handler.
+	msgType := mqmd.MsgType
+	dlh := ibmmq.NewMQDLH(mqmd)
+	mqmd.MsgType = msgType
+
+	dest := parseQueueName(destName)
+	dlh.Reason = ibmmq.MQRC_BACKOUT_THRESHOLD_REACHED
+	dlh.DestQName = dest.queueName
+	dlh.DestQMgrName = dest.queueManagerName
+
+	return append(dlh.Bytes(), body...)
+}
+
+// openTarget returns the handle for a destination queue, opening it the
+// first time that it is needed.
+func (mover *delayMover) openTarget(destName string) (ibmmq.MQObject, error) {
+
+	if target, ok := mover.targets[destName]; ok {
+		return target, nil
+	}
+
+	// The destination can be on a remote queue manager.
+	mqod := parseQueueName(destName).openDescriptor()
+
+	target, err := mover.qMgr.Open(mqod, ibmmq.MQOO_OUTPUT|ibmmq.MQOO_SET_ALL_CONTEXT|ibmmq.MQOO_FAIL_IF_QUIESCING)
+	if err == nil {
+		mover.targets[destName] = target
+	}
+
+	return target, err
+}
+
+// closeTarget closes the handle for a destination queue.
+func (mover *delayMover) closeTarget(destName string) {
+
+	if target, ok := mover.targets[destName]; ok {
+		target.Close(0)
+		delete(mover.targets, destName)
+	}
+}
+
+// close closes the queues and disconnects from the queue manager.
+func (mover *delayMover) close() {
+
+	for destName := range mover.targets {
+		mover.closeTarget(destName)
+	}
+	mover.staging.Close(0)
+	mover.qMgr.Disc()
+}
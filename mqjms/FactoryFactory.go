@@ -87,7 +87,7 @@ func CreateConnectionFactoryFromJSON(connectionInfoLocn string, apiKeyLocn strin
 		return ConnectionFactoryImpl{}, err
 	}
 
-	var qmName, hostname, appChannel string
+	var qmName, hostname, appChannel, connNameList string
 	var port int
 
 	qmName, errQM := parseStringValueFromJSON("queueManagerName", connInfoMap, connectionInfoLocn)
@@ -95,14 +95,30 @@ func CreateConnectionFactoryFromJSON(connectionInfoLocn string, apiKeyLocn strin
 		return ConnectionFactoryImpl{}, errQM
 	}
 
-	hostname, errHost := parseStringValueFromJSON("hostname", connInfoMap, connectionInfoLocn)
-	if errHost != nil {
-		return ConnectionFactoryImpl{}, errHost
+	// The connection name list is optional, but if it is specified then the
+	// hostname and port are not required.
+	if connInfoMap["connectionNameList"] != nil {
+		var errConnNames error
+		connNameList, errConnNames = parseConnectionNameListFromJSON("connectionNameList", connInfoMap, connectionInfoLocn)
+		if errConnNames != nil {
+			return ConnectionFactoryImpl{}, errConnNames
+		}
 	}
 
-	port, errPort := parseIntValueFromJSON("listenerPort", connInfoMap, connectionInfoLocn)
-	if errPort != nil {
-		return ConnectionFactoryImpl{}, errPort
+	if connNameList == "" || connInfoMap["hostname"] != nil {
+		var errHost error
+		hostname, errHost = parseStringValueFromJSON("hostname", connInfoMap, connectionInfoLocn)
+		if errHost != nil {
+			return ConnectionFactoryImpl{}, errHost
+		}
+	}
+
+	if connNameList == "" || connInfoMap["listenerPort"] != nil {
+		var errPort error
+		port, errPort = parseIntValueFromJSON("listenerPort", connInfoMap, connectionInfoLocn)
+		if errPort != nil {
+			return ConnectionFactoryImpl{}, errPort
+		}
 	}
 
 	appChannel, errChannel := parseStringValueFromJSON("applicationChannelName", connInfoMap, connectionInfoLocn)
@@ -134,12 +150,13 @@ func CreateConnectionFactoryFromJSON(connectionInfoLocn string, apiKeyLocn strin
 
 	// Use the parsed values to initialize the attributes of the Impl object.
 	cf = ConnectionFactoryImpl{
-		QMName:      qmName,
-		Hostname:    hostname,
-		PortNumber:  port,
-		ChannelName: appChannel,
-		UserName:    username,
-		Password:    password,
+		QMName:             qmName,
+		Hostname:           hostname,
+		PortNumber:         port,
+		ConnectionNameList: connNameList,
+		ChannelName:        appChannel,
+		UserName:           username,
+		Password:           password,
 	}
 
 	// Give the populated ConnectionFactory back to the caller.
@@ -176,3 +193,48 @@ func parseIntValueFromJSON(attributeName string, mapData map[string]*json.RawMes
 	return valueNum, err
 
 }
+
+// Extract a connection name list from the map that we generated from a JSON
+// object. The list can either be a string in the MQ format such as
+// "host1(1414),host2(1414)", or an ordered array of objects that each contain
+// a hostname and listenerPort attribute.
+func parseConnectionNameListFromJSON(attributeName string, mapData map[string]*json.RawMessage, fileName string) (value string, err error) {
+
+	if mapData[attributeName] == nil {
+		return "", errors.New("Unable to find " + attributeName + " in " + fileName)
+	}
+
+	// First try to read the value as a plain string.
+	var connNameStr string
+	err = json.Unmarshal(*mapData[attributeName], &connNameStr)
+	if err == nil {
+		return connNameStr, nil
+	}
+
+	// Otherwise it should be an array of hostname/port objects.
+	var connNameArray []map[string]*json.RawMessage
+	err = json.Unmarshal(*mapData[attributeName], &connNameArray)
+	if err != nil {
+		return "", errors.New("Unable to parse " + attributeName + " in " + fileName +
+			" as either a string or an array of hostname/listenerPort objects")
+	}
+
+	hostnames := make([]string, len(connNameArray))
+	ports := make([]int, len(connNameArray))
+
+	for i, connNameMap := range connNameArray {
+
+		hostnames[i], err = parseStringValueFromJSON("hostname", connNameMap, fileName)
+		if err != nil {
+			return "", err
+		}
+
+		ports[i], err = parseIntValueFromJSON("listenerPort", connNameMap, fileName)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return CreateConnectionNameList(hostnames, ports)
+
+}
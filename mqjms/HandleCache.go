@@ -0,0 +1,160 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"container/list"
+	"github.com/ibm-messaging/mq-golang/ibmmq"
+	"sync"
+)
+
+// handleCache keeps the queues that have been opened for sending messages
+// using a context, so that each Send doesn't have to open and close the queue.
+// When the cache is full the least recently used handle is closed.
+//
+// A handle can be in use by several goroutines at once, so a handle that is
+// removed from the cache is only closed once the last of them has released it.
+type handleCache struct {
+	size int
+
+	// Protects the attributes below, and the attributes of each cachedHandle.
+	mutex   sync.Mutex
+	handles map[string]*list.Element
+	lru     *list.List // Most recently used handle at the front
+}
+
+// cachedHandle is a queue handle that is owned by a handleCache.
+type cachedHandle struct {
+	name    string
+	qObject ibmmq.MQObject
+	users   int
+	removed bool
+}
+
+// newHandleCache returns a cache that keeps up to size handles open.
+func newHandleCache(size int) *handleCache {
+	return &handleCache{
+		size:    size,
+		handles: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// acquire returns the cached handle for the named queue, or else calls the
+// open function and adds the new handle to the cache. The boolean is true if
+// the handle came from the cache. The caller must pass the handle to release
+// when it has finished using it.
+func (cache *handleCache) acquire(name string,
+	open func() (ibmmq.MQObject, error)) (*cachedHandle, bool, error) {
+
+	cache.mutex.Lock()
+	if elem, ok := cache.handles[name]; ok {
+		cache.lru.MoveToFront(elem)
+		handle := elem.Value.(*cachedHandle)
+		handle.users++
+		cache.mutex.Unlock()
+		return handle, true, nil
+	}
+	cache.mutex.Unlock()
+
+	// Open the queue without holding the lock, so that sends to queues that
+	// are already open are not delayed.
+	qObject, err := open()
+	if err != nil {
+		return nil, false, err
+	}
+
+	handle := &cachedHandle{name: name, qObject: qObject, users: 1}
+
+	var evicted []*cachedHandle
+
+	cache.mutex.Lock()
+	if _, ok := cache.handles[name]; ok || cache.size <= 0 {
+		// Another goroutine opened the same queue at the same time (or the
+		// cache has been closed), so this handle is just used for one message.
+		handle.removed = true
+	} else {
+		cache.handles[name] = cache.lru.PushFront(handle)
+		for cache.lru.Len() > cache.size {
+			evicted = append(evicted, cache.removeLocked(cache.lru.Back())...)
+		}
+	}
+	cache.mutex.Unlock()
+
+	closeHandles(evicted)
+
+	return handle, false, nil
+}
+
+// release is called when the caller of acquire has finished using a handle,
+// and closes the handle if it has been removed from the cache.
+func (cache *handleCache) release(handle *cachedHandle) {
+
+	cache.mutex.Lock()
+	handle.users--
+	closeNow := handle.removed && handle.users == 0
+	cache.mutex.Unlock()
+
+	if closeNow {
+		handle.qObject.Close(0)
+	}
+}
+
+// invalidate removes a handle from the cache because it can no longer be used,
+// for example because the queue has been deleted. The handle is closed when
+// it is released.
+func (cache *handleCache) invalidate(handle *cachedHandle) {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if elem, ok := cache.handles[handle.name]; ok && elem.Value == handle {
+		cache.lru.Remove(elem)
+		delete(cache.handles, handle.name)
+	}
+	handle.removed = true
+}
+
+// close closes all of the handles in the cache that are not in use, and
+// stops any more handles from being cached.
+func (cache *handleCache) close() {
+
+	var closed []*cachedHandle
+
+	cache.mutex.Lock()
+	cache.size = 0
+	for cache.lru.Len() > 0 {
+		closed = append(closed, cache.removeLocked(cache.lru.Back())...)
+	}
+	cache.mutex.Unlock()
+
+	closeHandles(closed)
+}
+
+// removeLocked removes an element from the cache, returning its handle if it
+// is not in use and so should be closed by the caller. The mutex must be held.
+func (cache *handleCache) removeLocked(elem *list.Element) []*cachedHandle {
+
+	handle := cache.lru.Remove(elem).(*cachedHandle)
+	delete(cache.handles, handle.name)
+	handle.removed = true
+
+	if handle.users == 0 {
+		return []*cachedHandle{handle}
+	}
+	return nil
+}
+
+// closeHandles closes handles that have been removed from the cache.
+func closeHandles(handles []*cachedHandle) {
+	for _, handle := range handles {
+		handle.qObject.Close(0)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang/ibmmq"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The name of the MQ message property that carries the JMSType, which is the
+// same property that is used by the IBM MQ classes for JMS.
+const jmsTypePropertyName = "mcd.Type"
+
+// The names of the MQ message properties that carry the JMSDeliveryTime of a
+// message that was sent with a delivery delay, and the name of the queue that
+// it is moved to from the staging queue when it is due.
+const deliveryTimePropertyName = "gojms.DeliveryTime"
+const delayDestinationPropertyName = "gojms.Destination"
+
+// The header properties that are sent and received along with the application
+// properties of a message.
+var headerPropertyNames = []string{jmsTypePropertyName, deliveryTimePropertyName, delayDestinationPropertyName}
+
+// The folders of MQ message properties that are used for JMS header fields
+// and MQ internal properties, which are not returned as application
+// properties. Application properties are held in the usr folder.
+var reservedPropertyPrefixes = []string{"mcd.", "jms.", "mqext.", "mqps.", "mq.", "gojms."}
+
+// The prefix of the folder that holds application properties, which MQ may
+// include in the names of the properties that it returns.
+const userPropertyPrefix = "usr."
+
+// validatePropertyName checks that a name can be used for an application
+// property.
+func validatePropertyName(name string) jms20subset.JMSException {
+
+	if name == "" {
+		return jms20subset.CreateJMSException("Property name must not be empty", "InvalidPropertyName", nil)
+	}
+
+	if isReservedPropertyName(name) {
+		return jms20subset.CreateJMSException("Property name "+name+" is in a folder that is reserved for "+
+			"JMS header fields and MQ properties", "InvalidPropertyName", nil)
+	}
+
+	return nil
+}
+
+// sortedPropertyNames returns the names of the properties in a consistent order.
+func sortedPropertyNames(properties map[string]interface{}) []string {
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// createMessageFormatException returns the error that is given to an
+// application that reads a property as a type that it cannot be converted to.
+func createMessageFormatException(name string, typeName string) jms20subset.JMSException {
+	return jms20subset.CreateJMSException("Unable to convert the value of property "+name+" to "+typeName,
+		"MessageFormatException", nil)
+}
+
+// propertyToString converts a property value to a string, which is possible
+// for every type of property.
+func propertyToString(value interface{}) string {
+
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue
+	case int:
+		return strconv.Itoa(typedValue)
+	case bool:
+		return strconv.FormatBool(typedValue)
+	case float64:
+		return strconv.FormatFloat(typedValue, 'g', -1, 64)
+	case []byte:
+		return string(typedValue)
+	}
+
+	return ""
+}
+
+// propertyToInt converts a property value to an int, which is possible for
+// integer properties and strings that contain an integer.
+func propertyToInt(name string, value interface{}) (int, jms20subset.JMSException) {
+
+	switch typedValue := value.(type) {
+	case int:
+		return typedValue, nil
+	case string:
+		if intValue, err := strconv.Atoi(typedValue); err == nil {
+			return intValue, nil
+		}
+	}
+
+	return 0, createMessageFormatException(name, "int")
+}
+
+// propertyToBool converts a property value to a bool, which is possible for
+// boolean properties and strings that contain "true" or "false".
+func propertyToBool(name string, value interface{}) (bool, jms20subset.JMSException) {
+
+	switch typedValue := value.(type) {
+	case bool:
+		return typedValue, nil
+	case string:
+		if boolValue, err := strconv.ParseBool(typedValue); err == nil {
+			return boolValue, nil
+		}
+	case nil:
+		// A missing property is treated as false, as in Java JMS.
+		return false, nil
+	}
+
+	return false, createMessageFormatException(name, "bool")
+}
+
+// propertyToFloat converts a property value to a float64, which is possible
+// for floating point properties and strings that contain a number.
+func propertyToFloat(name string, value interface{}) (float64, jms20subset.JMSException) {
+
+	switch typedValue := value.(type) {
+	case float64:
+		return typedValue, nil
+	case string:
+		if floatValue, err := strconv.ParseFloat(typedValue, 64); err == nil {
+			return floatValue, nil
+		}
+	}
+
+	return 0, createMessageFormatException(name, "float64")
+}
+
+// normalisePropertyValue converts a property value that was received from MQ
+// into one of the types that are used to hold properties in a message.
+func normalisePropertyValue(value interface{}) interface{} {
+
+	switch typedValue := value.(type) {
+	case int8:
+		return int(typedValue)
+	case int16:
+		return int(typedValue)
+	case int32:
+		return int(typedValue)
+	case int64:
+		return int(typedValue)
+	case float32:
+		return float64(typedValue)
+	}
+
+	return value
+}
+
+// createPropertiesHandle creates an MQ message handle that contains the
+// application properties and header properties (such as the JMSType) of a
+// message, so that they can be sent with the message. The handle must be
+// deleted once the message has been sent.
+func createPropertiesHandle(qMgr ibmmq.MQQueueManager, properties map[string]interface{},
+	headers map[string]interface{}) (ibmmq.MQMessageHandle, error) {
+
+	msgHandle, err := qMgr.CrtMH(ibmmq.NewMQCMHO())
+	if err != nil {
+		return msgHandle, err
+	}
+
+	smpo := ibmmq.NewMQSMPO()
+	pd := ibmmq.NewMQPD()
+
+	for _, name := range sortedPropertyNames(headers) {
+		if err != nil {
+			break
+		}
+		err = msgHandle.SetMP(smpo, name, pd, headers[name])
+	}
+
+	for _, name := range sortedPropertyNames(properties) {
+		if err != nil {
+			break
+		}
+		err = msgHandle.SetMP(smpo, name, pd, properties[name])
+	}
+
+	if err != nil {
+		msgHandle.DltMH(ibmmq.NewMQDMHO())
+	}
+
+	return msgHandle, err
+}
+
+// readPropertiesHandle reads the application properties and header
+// properties of a received message from the message handle that was used to
+// get it.
+func readPropertiesHandle(msgHandle ibmmq.MQMessageHandle) (map[string]interface{}, map[string]interface{}, error) {
+
+	properties := make(map[string]interface{})
+	headers := make(map[string]interface{})
+
+	impo := ibmmq.NewMQIMPO()
+	pd := ibmmq.NewMQPD()
+
+	impo.Options = ibmmq.MQIMPO_CONVERT_VALUE | ibmmq.MQIMPO_INQ_FIRST
+
+	for {
+		name, value, err := msgHandle.InqMP(impo, pd, "%")
+		if err != nil {
+			if err.(*ibmmq.MQReturn).MQRC == ibmmq.MQRC_PROPERTY_NOT_AVAILABLE {
+				// There are no more properties.
+				break
+			}
+			return nil, nil, err
+		}
+
+		impo.Options = ibmmq.MQIMPO_CONVERT_VALUE | ibmmq.MQIMPO_INQ_NEXT
+
+		if isReservedPropertyName(name) {
+			for _, headerName := range headerPropertyNames {
+				if strings.EqualFold(name, headerName) {
+					headers[headerName] = normalisePropertyValue(value)
+				}
+			}
+			continue
+		}
+
+		properties[strings.TrimPrefix(name, userPropertyPrefix)] = normalisePropertyValue(value)
+	}
+
+	// The header properties are in folders that are not always returned by the
+	// wildcard inquiry, so ask for any that are missing by name.
+	for _, headerName := range headerPropertyNames {
+		if _, ok := headers[headerName]; ok {
+			continue
+		}
+		impo.Options = ibmmq.MQIMPO_CONVERT_VALUE | ibmmq.MQIMPO_INQ_FIRST
+		if _, value, err := msgHandle.InqMP(impo, pd, headerName); err == nil {
+			headers[headerName] = normalisePropertyValue(value)
+		}
+	}
+
+	return properties, headers, nil
+}
+
+// isReservedPropertyName returns true if a property that was received from
+// MQ is not an application property.
+func isReservedPropertyName(name string) bool {
+
+	for _, prefix := range reservedPropertyPrefixes {
+		if strings.HasPrefix(strings.ToLower(name), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
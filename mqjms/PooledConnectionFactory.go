@@ -0,0 +1,658 @@
+// Copyright (c) IBM Corporation 2019.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License 2.0, which is available at
+// http://www.eclipse.org/legal/epl-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0
+
+//
+package mqjms
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PooledConnectionFactory is a ConnectionFactory that keeps the contexts that
+// the application has finished with, so that they can be handed out again by
+// a later call to CreateContext without the cost of connecting to the queue
+// manager. This suits applications that create a context for each unit of
+// work, such as an HTTP request handler.
+//
+// The contexts are created by the wrapped Factory, which can be any
+// jms20subset.ConnectionFactory (typically a ConnectionFactoryImpl). Calling
+// Close on a context that was obtained from the pool returns it to the pool
+// instead of disconnecting it, and closes any consumers that were created from
+// it. Producers created from a pooled context return an IllegalStateException
+// if they are used after it has been closed.
+//
+// The properties must be set before the first call to CreateContext, and not
+// changed afterwards. A PooledConnectionFactory must not be copied after it has
+// been used, so it should be used by pointer, for example;
+//
+//	pool := &mqjms.PooledConnectionFactory{Factory: cf, MaxActive: 20}
+//	context, err := pool.CreateContext()
+type PooledConnectionFactory struct {
+	Factory jms20subset.ConnectionFactory
+
+	// The maximum number of contexts that can be in use at the same time. The
+	// default of zero means that there is no limit.
+	MaxActive int
+
+	// The number of milliseconds that CreateContext waits for a context to be
+	// returned to the pool if MaxActive contexts are already in use. The
+	// default of zero means that an error is returned straight away.
+	MaxWait int
+
+	// The maximum number of idle contexts that are kept in the pool, with any
+	// others being closed when they are returned. Defaults to PoolMaxIdle_DEFAULT.
+	MaxIdle int
+
+	// The maximum number of seconds that a context is used for after it was
+	// created, after which it is closed instead of being reused, for example
+	// to spread connections across queue managers again after a failover. The
+	// default of zero means that there is no limit.
+	MaxLifetime int
+
+	// Checks that an idle context can still communicate with the queue manager
+	// before handing it out, so that a broken connection is not given to the
+	// application. This costs a round trip to the queue manager.
+	TestOnBorrow bool
+
+	initOnce sync.Once
+	slots    chan struct{}
+
+	// Protects the attributes below.
+	mutex  sync.Mutex
+	idle   []*pooledEntry
+	closed bool
+	stats  PoolStats
+}
+
+// PoolStats contains statistics about the use of a PooledConnectionFactory.
+type PoolStats struct {
+	Active             int // Contexts currently in use by the application
+	Idle               int // Contexts currently waiting in the pool
+	Created            int // Contexts created by the wrapped ConnectionFactory
+	Destroyed          int // Contexts closed by the pool
+	Borrowed           int // Calls to CreateContext that returned a context
+	Reused             int // Calls to CreateContext that returned an idle context
+	Waited             int // Calls to CreateContext that waited for a context
+	Exhausted          int // Calls to CreateContext that failed with ErrorCode_POOL_EXHAUSTED
+	ValidationFailures int // Idle contexts that were closed because they no longer worked
+}
+
+// pooledEntry holds a context that is owned by the pool.
+type pooledEntry struct {
+	ctx     jms20subset.JMSContext
+	created time.Time
+}
+
+// CreateContext returns a context from the pool if there is an idle one,
+// or else creates a new context using the wrapped ConnectionFactory.
+func (pool *PooledConnectionFactory) CreateContext() (jms20subset.JMSContext, jms20subset.JMSException) {
+
+	pool.initOnce.Do(func() {
+		if pool.MaxActive > 0 {
+			pool.slots = make(chan struct{}, pool.MaxActive)
+		}
+	})
+
+	if pool.isClosed() {
+		return nil, createIllegalStateException("PooledConnectionFactory")
+	}
+
+	if exhaustedErr := pool.acquireSlot(); exhaustedErr != nil {
+		return nil, exhaustedErr
+	}
+
+	entry, err := pool.borrow()
+	if err != nil {
+		pool.releaseSlot()
+		return nil, err
+	}
+
+	return &pooledContext{pool: pool, entry: entry}, nil
+}
+
+// Stats returns the current statistics of the pool.
+func (pool *PooledConnectionFactory) Stats() PoolStats {
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	stats := pool.stats
+	stats.Idle = len(pool.idle)
+
+	return stats
+}
+
+// Close closes the idle contexts in the pool, and stops any more contexts
+// from being handed out. Contexts that are in use by the application are
+// closed when they are returned to the pool.
+func (pool *PooledConnectionFactory) Close() {
+
+	pool.mutex.Lock()
+	pool.closed = true
+	idle := pool.idle
+	pool.idle = nil
+	pool.mutex.Unlock()
+
+	for _, entry := range idle {
+		pool.destroy(entry)
+	}
+}
+
+// acquireSlot reserves one of the MaxActive contexts for the caller, waiting
+// for up to MaxWait milliseconds for one to become available.
+func (pool *PooledConnectionFactory) acquireSlot() jms20subset.JMSException {
+
+	if pool.slots == nil {
+		return nil
+	}
+
+	select {
+	case pool.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if pool.MaxWait > 0 {
+		pool.mutex.Lock()
+		pool.stats.Waited++
+		pool.mutex.Unlock()
+
+		timer := time.NewTimer(time.Duration(pool.MaxWait) * time.Millisecond)
+		defer timer.Stop()
+
+		select {
+		case pool.slots <- struct{}{}:
+			return nil
+		case <-timer.C:
+		}
+	}
+
+	pool.mutex.Lock()
+	pool.stats.Exhausted++
+	pool.mutex.Unlock()
+
+	return jms20subset.CreateJMSException("All "+strconv.Itoa(pool.MaxActive)+
+		" contexts in the pool are in use", ErrorCode_POOL_EXHAUSTED, nil)
+}
+
+// releaseSlot frees up a slot reserved by acquireSlot.
+func (pool *PooledConnectionFactory) releaseSlot() {
+	if pool.slots != nil {
+		<-pool.slots
+	}
+}
+
+// borrow takes an idle context from the pool, discarding any that have
+// expired or no longer work, or creates a new one if there are none left.
+func (pool *PooledConnectionFactory) borrow() (*pooledEntry, jms20subset.JMSException) {
+
+	for {
+		pool.mutex.Lock()
+		var entry *pooledEntry
+		if len(pool.idle) > 0 {
+			// Use the most recently returned context, so that the pool can
+			// shrink back down if it is not being used as heavily.
+			entry = pool.idle[len(pool.idle)-1]
+			pool.idle = pool.idle[:len(pool.idle)-1]
+		}
+		pool.mutex.Unlock()
+
+		if entry == nil {
+			break
+		}
+
+		if pool.isExpired(entry) {
+			pool.destroy(entry)
+			continue
+		}
+
+		if !pool.isUsable(entry, pool.TestOnBorrow) {
+			pool.mutex.Lock()
+			pool.stats.ValidationFailures++
+			pool.mutex.Unlock()
+
+			pool.destroy(entry)
+			continue
+		}
+
+		pool.mutex.Lock()
+		pool.stats.Active++
+		pool.stats.Borrowed++
+		pool.stats.Reused++
+		pool.mutex.Unlock()
+
+		return entry, nil
+	}
+
+	ctx, err := pool.Factory.CreateContext()
+	if err != nil {
+		return nil, err
+	}
+
+	pool.mutex.Lock()
+	pool.stats.Active++
+	pool.stats.Borrowed++
+	pool.stats.Created++
+	pool.mutex.Unlock()
+
+	return &pooledEntry{ctx: ctx, created: time.Now()}, nil
+}
+
+// giveBack is called when the application closes a pooled context, and
+// either keeps the context for reuse or closes it.
+func (pool *PooledConnectionFactory) giveBack(entry *pooledEntry) {
+
+	defer pool.releaseSlot()
+
+	maxIdle := pool.MaxIdle
+	if maxIdle <= 0 {
+		maxIdle = PoolMaxIdle_DEFAULT
+	}
+
+	keep := !pool.isExpired(entry) && pool.isUsable(entry, false)
+
+	pool.mutex.Lock()
+	pool.stats.Active--
+	if keep && !pool.closed && len(pool.idle) < maxIdle {
+		pool.idle = append(pool.idle, entry)
+		pool.mutex.Unlock()
+		return
+	}
+	pool.mutex.Unlock()
+
+	pool.destroy(entry)
+}
+
+// destroy closes a context that is owned by the pool.
+func (pool *PooledConnectionFactory) destroy(entry *pooledEntry) {
+
+	entry.ctx.Close()
+
+	pool.mutex.Lock()
+	pool.stats.Destroyed++
+	pool.mutex.Unlock()
+}
+
+// isExpired returns true if the context has reached its MaxLifetime.
+func (pool *PooledConnectionFactory) isExpired(entry *pooledEntry) bool {
+	return pool.MaxLifetime > 0 &&
+		time.Since(entry.created) >= time.Duration(pool.MaxLifetime)*time.Second
+}
+
+// isUsable returns false if the context is known to be broken, because a call
+// made using it found that the connection is broken or the client failed to
+// reconnect to the queue manager, optionally checking the connection with a
+// call to the queue manager.
+func (pool *PooledConnectionFactory) isUsable(entry *pooledEntry, ping bool) bool {
+
+	ctxImpl, ok := entry.ctx.(*ContextImpl)
+	if !ok {
+		// There is no way to check contexts from other providers.
+		return true
+	}
+
+	if ping {
+		return ctxImpl.ping() == nil
+	}
+
+	return ctxImpl.checkState() == nil
+}
+
+// isClosed returns true if the pool has been closed.
+func (pool *PooledConnectionFactory) isClosed() bool {
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return pool.closed
+}
+
+// pooledContext is the JMSContext that is given to the application by a
+// PooledConnectionFactory, which returns the underlying context to the pool
+// when it is closed.
+type pooledContext struct {
+	pool  *PooledConnectionFactory
+	entry *pooledEntry
+
+	// Protects the attributes below.
+	mutex     sync.Mutex
+	closed    bool
+	consumers []jms20subset.JMSConsumer
+}
+
+// CreateContext creates a new context that shares the connection of the
+// underlying context. The new context is not part of the pool.
+func (ctx *pooledContext) CreateContext(sessionMode int) (jms20subset.JMSContext, jms20subset.JMSException) {
+
+	if ctx.isClosed() {
+		return nil, createIllegalStateException("JMSContext")
+	}
+
+	return ctx.entry.ctx.CreateContext(sessionMode)
+}
+
+// GetSessionMode returns the session mode of the underlying context.
+func (ctx *pooledContext) GetSessionMode() int {
+	return ctx.entry.ctx.GetSessionMode()
+}
+
+// CreateProducer creates a producer using the underlying context, which can
+// no longer be used once this context is returned to the pool.
+func (ctx *pooledContext) CreateProducer() jms20subset.JMSProducer {
+	return &pooledProducer{ctx: ctx, producer: ctx.entry.ctx.CreateProducer()}
+}
+
+// CreateProducerForDestination creates a producer using the underlying
+// context, which can no longer be used once this context is returned to the
+// pool.
+func (ctx *pooledContext) CreateProducerForDestination(dest jms20subset.Destination) jms20subset.JMSProducer {
+	return &pooledProducer{ctx: ctx, producer: ctx.entry.ctx.CreateProducerForDestination(dest)}
+}
+
+// CreateConsumer creates a consumer using the underlying context, which is
+// closed automatically when this context is returned to the pool.
+func (ctx *pooledContext) CreateConsumer(dest jms20subset.Destination) (jms20subset.JMSConsumer, jms20subset.JMSException) {
+	return ctx.CreateConsumerWithSelector(dest, "")
+}
+
+// CreateConsumerWithSelector creates a consumer using the underlying context,
+// which is closed automatically when this context is returned to the pool.
+func (ctx *pooledContext) CreateConsumerWithSelector(dest jms20subset.Destination, selector string) (jms20subset.JMSConsumer, jms20subset.JMSException) {
+
+	if ctx.isClosed() {
+		return nil, createIllegalStateException("JMSContext")
+	}
+
+	var consumer jms20subset.JMSConsumer
+	var err jms20subset.JMSException
+
+	if selector == "" {
+		consumer, err = ctx.entry.ctx.CreateConsumer(dest)
+	} else {
+		consumer, err = ctx.entry.ctx.CreateConsumerWithSelector(dest, selector)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	if ctx.closed {
+		consumer.Close()
+		return nil, createIllegalStateException("JMSContext")
+	}
+
+	ctx.consumers = append(ctx.consumers, consumer)
+
+	return consumer, nil
+}
+
+// CreateQueue creates a queue object using the underlying context, or returns
+// nil if this context has been returned to the pool.
+func (ctx *pooledContext) CreateQueue(queueName string) jms20subset.Queue {
+
+	if ctx.isClosed() {
+		return nil
+	}
+
+	return ctx.entry.ctx.CreateQueue(queueName)
+}
+
+// CreateTextMessage creates a message using the underlying context.
+func (ctx *pooledContext) CreateTextMessage() jms20subset.TextMessage {
+	return ctx.entry.ctx.CreateTextMessage()
+}
+
+// CreateTextMessageWithString creates a message using the underlying context.
+func (ctx *pooledContext) CreateTextMessageWithString(txt string) jms20subset.TextMessage {
+	return ctx.entry.ctx.CreateTextMessageWithString(txt)
+}
+
+// SetExceptionListener registers a listener on the underlying context, which
+// is removed again when this context is returned to the pool.
+func (ctx *pooledContext) SetExceptionListener(listener func(jms20subset.JMSException)) jms20subset.JMSException {
+
+	if ctx.isClosed() {
+		return createIllegalStateException("JMSContext")
+	}
+
+	return ctx.entry.ctx.SetExceptionListener(listener)
+}
+
+// Close returns the underlying context to the pool, after closing any
+// consumers that were created from it. Calling Close more than once has no
+// effect.
+func (ctx *pooledContext) Close() {
+
+	ctx.mutex.Lock()
+	if ctx.closed {
+		ctx.mutex.Unlock()
+		return
+	}
+	ctx.closed = true
+	consumers := ctx.consumers
+	ctx.consumers = nil
+	ctx.mutex.Unlock()
+
+	for _, consumer := range consumers {
+		consumer.Close()
+	}
+
+	// Make sure the next user of the context doesn't receive our events, or
+	// the outcome of our asynchronous sends.
+	ctx.entry.ctx.SetExceptionListener(nil)
+	if ctxImpl, ok := ctx.entry.ctx.(*ContextImpl); ok {
+		ctxImpl.conn.asyncSends.check()
+	}
+
+	ctx.pool.giveBack(ctx.entry)
+}
+
+// isClosed returns true if the application has closed this context.
+func (ctx *pooledContext) isClosed() bool {
+
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	return ctx.closed
+}
+
+// pooledProducer is the JMSProducer that is given to the application by a
+// pooled context. It stops messages from being sent once the context has been
+// returned to the pool, as the underlying context may then have been handed
+// out to another part of the application.
+type pooledProducer struct {
+	ctx      *pooledContext
+	producer jms20subset.JMSProducer
+}
+
+// Send sends a message using the underlying producer.
+func (producer *pooledProducer) Send(dest jms20subset.Destination, msg jms20subset.Message) jms20subset.JMSException {
+
+	if producer.ctx.isClosed() {
+		return createIllegalStateException("JMSContext")
+	}
+
+	return producer.producer.Send(dest, msg)
+}
+
+// SendString sends a message using the underlying producer.
+func (producer *pooledProducer) SendString(dest jms20subset.Destination, body string) jms20subset.JMSException {
+
+	if producer.ctx.isClosed() {
+		return createIllegalStateException("JMSContext")
+	}
+
+	return producer.producer.SendString(dest, body)
+}
+
+// SendWithOptions sends a message using the underlying producer.
+func (producer *pooledProducer) SendWithOptions(dest jms20subset.Destination, msg jms20subset.Message,
+	options jms20subset.SendOptions) jms20subset.JMSException {
+
+	if producer.ctx.isClosed() {
+		return createIllegalStateException("JMSContext")
+	}
+
+	return producer.producer.SendWithOptions(dest, msg, options)
+}
+
+// SendBatch sends the messages using the underlying producer.
+func (producer *pooledProducer) SendBatch(dest jms20subset.Destination, msgs []jms20subset.Message,
+	options jms20subset.BatchOptions) ([]jms20subset.SendResult, jms20subset.JMSException) {
+
+	if producer.ctx.isClosed() {
+		return nil, createIllegalStateException("JMSContext")
+	}
+
+	return producer.producer.SendBatch(dest, msgs, options)
+}
+
+// GetDestination returns the destination of the underlying producer.
+func (producer *pooledProducer) GetDestination() jms20subset.Destination {
+	return producer.producer.GetDestination()
+}
+
+// SetDeliveryMode sets the delivery mode of the underlying producer.
+func (producer *pooledProducer) SetDeliveryMode(mode int) jms20subset.JMSProducer {
+	producer.producer.SetDeliveryMode(mode)
+	return producer
+}
+
+// GetDeliveryMode returns the delivery mode of the underlying producer.
+func (producer *pooledProducer) GetDeliveryMode() int {
+	return producer.producer.GetDeliveryMode()
+}
+
+// SetTimeToLive sets the time to live of the underlying producer.
+func (producer *pooledProducer) SetTimeToLive(timeToLive int) jms20subset.JMSProducer {
+	producer.producer.SetTimeToLive(timeToLive)
+	return producer
+}
+
+// GetTimeToLive returns the time to live of the underlying producer.
+func (producer *pooledProducer) GetTimeToLive() int {
+	return producer.producer.GetTimeToLive()
+}
+
+// SetDeliveryDelay sets the delivery delay of the underlying producer.
+func (producer *pooledProducer) SetDeliveryDelay(deliveryDelay int) jms20subset.JMSProducer {
+	producer.producer.SetDeliveryDelay(deliveryDelay)
+	return producer
+}
+
+// GetDeliveryDelay returns the delivery delay of the underlying producer.
+func (producer *pooledProducer) GetDeliveryDelay() int {
+	return producer.producer.GetDeliveryDelay()
+}
+
+// SetDisableMessageID sets the message ID hint of the underlying producer.
+func (producer *pooledProducer) SetDisableMessageID(value bool) jms20subset.JMSProducer {
+	producer.producer.SetDisableMessageID(value)
+	return producer
+}
+
+// GetDisableMessageID returns the message ID hint of the underlying producer.
+func (producer *pooledProducer) GetDisableMessageID() bool {
+	return producer.producer.GetDisableMessageID()
+}
+
+// SetDisableMessageTimestamp sets the timestamp hint of the underlying producer.
+func (producer *pooledProducer) SetDisableMessageTimestamp(value bool) jms20subset.JMSProducer {
+	producer.producer.SetDisableMessageTimestamp(value)
+	return producer
+}
+
+// GetDisableMessageTimestamp returns the timestamp hint of the underlying producer.
+func (producer *pooledProducer) GetDisableMessageTimestamp() bool {
+	return producer.producer.GetDisableMessageTimestamp()
+}
+
+// SetJMSCorrelationID sets the correlation ID of the underlying producer.
+func (producer *pooledProducer) SetJMSCorrelationID(correlID string) jms20subset.JMSProducer {
+	producer.producer.SetJMSCorrelationID(correlID)
+	return producer
+}
+
+// GetJMSCorrelationID returns the correlation ID of the underlying producer.
+func (producer *pooledProducer) GetJMSCorrelationID() string {
+	return producer.producer.GetJMSCorrelationID()
+}
+
+// SetJMSReplyTo sets the reply destination of the underlying producer.
+func (producer *pooledProducer) SetJMSReplyTo(dest jms20subset.Destination) jms20subset.JMSProducer {
+	producer.producer.SetJMSReplyTo(dest)
+	return producer
+}
+
+// GetJMSReplyTo returns the reply destination of the underlying producer.
+func (producer *pooledProducer) GetJMSReplyTo() jms20subset.Destination {
+	return producer.producer.GetJMSReplyTo()
+}
+
+// SetJMSType sets the message type of the underlying producer.
+func (producer *pooledProducer) SetJMSType(jmsType string) jms20subset.JMSProducer {
+	producer.producer.SetJMSType(jmsType)
+	return producer
+}
+
+// GetJMSType returns the message type of the underlying producer.
+func (producer *pooledProducer) GetJMSType() string {
+	return producer.producer.GetJMSType()
+}
+
+// SetStringProperty sets a property on the underlying producer.
+func (producer *pooledProducer) SetStringProperty(name string, value string) jms20subset.JMSProducer {
+	producer.producer.SetStringProperty(name, value)
+	return producer
+}
+
+// SetIntProperty sets a property on the underlying producer.
+func (producer *pooledProducer) SetIntProperty(name string, value int) jms20subset.JMSProducer {
+	producer.producer.SetIntProperty(name, value)
+	return producer
+}
+
+// SetBooleanProperty sets a property on the underlying producer.
+func (producer *pooledProducer) SetBooleanProperty(name string, value bool) jms20subset.JMSProducer {
+	producer.producer.SetBooleanProperty(name, value)
+	return producer
+}
+
+// SetDoubleProperty sets a property on the underlying producer.
+func (producer *pooledProducer) SetDoubleProperty(name string, value float64) jms20subset.JMSProducer {
+	producer.producer.SetDoubleProperty(name, value)
+	return producer
+}
+
+// GetPropertyNames returns the property names of the underlying producer.
+func (producer *pooledProducer) GetPropertyNames() []string {
+	return producer.producer.GetPropertyNames()
+}
+
+// ClearProperties clears the properties of the underlying producer.
+func (producer *pooledProducer) ClearProperties() jms20subset.JMSProducer {
+	producer.producer.ClearProperties()
+	return producer
+}
+
+// SetAsync sets the CompletionListener of the underlying producer.
+func (producer *pooledProducer) SetAsync(listener jms20subset.CompletionListener) jms20subset.JMSProducer {
+	producer.producer.SetAsync(listener)
+	return producer
+}
+
+// GetAsync returns the CompletionListener of the underlying producer.
+func (producer *pooledProducer) GetAsync() jms20subset.CompletionListener {
+	return producer.producer.GetAsync()
+}
@@ -15,19 +15,48 @@ import (
 	"github.com/ibm-messaging/mq-golang/ibmmq"
 	"log"
 	"strconv"
+	"sync"
+	"time"
 )
 
 // ProducerImpl defines a struct that contains the necessary objects for
 // sending messages to a queue on an IBM MQ queue manager.
+//
+// A ProducerImpl is safe for concurrent use by multiple goroutines, however
+// changing an option such as the delivery mode affects every message that is
+// subsequently sent by the producer, including those sent by other goroutines.
+//
+// SendWithOptions can be used to send an individual message with different
+// options without affecting other goroutines.
 type ProducerImpl struct {
-	ctx          ContextImpl
-	deliveryMode int
-	timeToLive   int
+	ctx  *ContextImpl
+	dest jms20subset.Destination // Set if the producer only sends to one destination
+
+	// Protects the message options below.
+	mutex         sync.Mutex
+	deliveryMode  int
+	timeToLive    int
+	deliveryDelay int
+	defaults      messageDefaults
+	listener      jms20subset.CompletionListener
+
+	// Hints that are recorded but not acted on, see SetDisableMessageID.
+	disableMessageID        bool
+	disableMessageTimestamp bool
+}
+
+// messageDefaults contains the header fields and properties that a producer
+// applies to each message that it sends, unless the message has its own value.
+type messageDefaults struct {
+	correlationID string
+	replyTo       jms20subset.Destination
+	jmsType       string
+	properties    map[string]interface{}
 }
 
 // Send a TextMessage with the specified body to the specified Destination
 // using any message options that are defined on this JMSProducer.
-func (producer ProducerImpl) SendString(dest jms20subset.Destination, bodyStr string) jms20subset.JMSException {
+func (producer *ProducerImpl) SendString(dest jms20subset.Destination, bodyStr string) jms20subset.JMSException {
 
 	// This is essentially just a helper method that avoids the application having
 	// to create its own TextMessage object.
@@ -40,102 +69,601 @@ func (producer ProducerImpl) SendString(dest jms20subset.Destination, bodyStr st
 
 // Send a message to the specified IBM MQ queue, using the message options
 // that are defined on this JMSProducer.
-func (producer ProducerImpl) Send(dest jms20subset.Destination, msg jms20subset.Message) jms20subset.JMSException {
+func (producer *ProducerImpl) Send(dest jms20subset.Destination, msg jms20subset.Message) jms20subset.JMSException {
+	return producer.SendWithOptions(dest, msg, jms20subset.SendOptions{})
+}
 
-	// Set up the basic objects we need to send the message.
-	mqod := ibmmq.NewMQOD()
+// SendWithOptions sends a message to the specified IBM MQ queue, using the
+// message options in the SendOptions in place of those that are defined on
+// this JMSProducer.
+func (producer *ProducerImpl) SendWithOptions(dest jms20subset.Destination, msg jms20subset.Message,
+	options jms20subset.SendOptions) jms20subset.JMSException {
 
-	var openOptions int32
-	openOptions = ibmmq.MQOO_OUTPUT + ibmmq.MQOO_FAIL_IF_QUIESCING
-	openOptions |= ibmmq.MQOO_INPUT_AS_Q_DEF
+	// A producer cannot be used once the context that created it is closed, or
+	// if its connection could not be reconnected after a failure.
+	if stateErr := producer.ctx.checkState(); stateErr != nil {
+		return stateErr
+	}
 
-	mqod.ObjectType = ibmmq.MQOT_Q
-	mqod.ObjectName = dest.GetDestinationName()
+	dest, destErr := producer.resolveDestination(dest)
+	if destErr != nil {
+		return destErr
+	}
 
-	var retErr jms20subset.JMSException
+	if optionsErr := validateSendOptions(options); optionsErr != nil {
+		return optionsErr
+	}
 
-	// Invoke the MQ command to open the queue, and register a defer hook
-	// to automatically close the object once we exit this function.
-	qObject, err := producer.ctx.qMgr.Open(mqod, openOptions)
-	if (ibmmq.MQObject{}) != qObject {
-		defer qObject.Close(0)
+	settings, settingsErr := producer.getSendSettings(options)
+	if settingsErr != nil {
+		return settingsErr
 	}
 
-	if err == nil {
+	// Prepare to send the message.
+	prepared, err := producer.prepareMessage(msg, toQueueImpl(dest).uri(), settings)
 
-		// Successfully opened the queue, so now prepare to send the message.
-		putmqmd := ibmmq.NewMQMD()
-		pmo := ibmmq.NewMQPMO()
+	// Invoke the MQ command to put the message.
+	// Any Err that occurs will be handled below.
+	if err == nil {
+		defer prepared.release()
 
-		// Configure the put message options, including asking MQ to allocate a
-		// unique message ID
-		pmo.Options = ibmmq.MQPMO_NO_SYNCPOINT | ibmmq.MQPMO_NEW_MSG_ID
+		put := func() error {
+			return producer.putToQueue(prepared.queueName, prepared.mqmd, prepared.pmo, prepared.buffer)
+		}
 
-		// Convert the JMS persistence into the equivalent MQ message descriptor
-		// attribute.
-		if producer.deliveryMode == jms20subset.DeliveryMode_NON_PERSISTENT {
-			putmqmd.Persistence = ibmmq.MQPER_NOT_PERSISTENT
+		if settings.listener != nil {
+			// Ask the queue manager not to send back the outcome of the put, which
+			// is found out later by the context instead.
+			prepared.pmo.Options |= ibmmq.MQPMO_ASYNC_RESPONSE
+			err = producer.ctx.conn.asyncSends.send(msg, parseQueueName(prepared.queueName), settings.listener, put)
 		} else {
-			putmqmd.Persistence = ibmmq.MQPER_PERSISTENT
+			err = put()
 		}
 
-		var buffer []byte
+		if err == nil {
+			prepared.markSent()
+		}
+	}
+
+	// Note that the following block handles errors for both opening the queue
+	// and putting the message.
+	if err != nil {
+		producer.ctx.conn.noteError(err)
+		return createMQException(err)
+	}
 
-		// We have a "Message" object and can use a switch to safely convert it
-		// to the sub-types in order to convert it appropriately into an MQ message
-		// object.
-		switch typedMsg := msg.(type) {
-		case *TextMessageImpl:
+	return nil
 
-			// If the message already has an MQMD then use that (for example it might
-			// contain ReplyTo information)
-			if typedMsg.mqmd != nil {
-				putmqmd = typedMsg.mqmd
+}
+
+// SendBatch sends the messages to the specified IBM MQ queue using the
+// message options that are defined on this JMSProducer, keeping the queue
+// open for the whole batch.
+//
+// If the batch is transacted then each chunk of messages is put under
+// syncpoint and committed once all of its messages have been put. If any put
+// in a chunk fails then the chunk is backed out and the remaining messages
+// are not sent, so that the application can retry from the first failed
+// message. Otherwise a failed message is reported and the remaining messages
+// are still sent.
+func (producer *ProducerImpl) SendBatch(dest jms20subset.Destination, msgs []jms20subset.Message,
+	options jms20subset.BatchOptions) ([]jms20subset.SendResult, jms20subset.JMSException) {
+
+	if stateErr := producer.ctx.checkState(); stateErr != nil {
+		return nil, stateErr
+	}
+
+	dest, destErr := producer.resolveDestination(dest)
+	if destErr != nil {
+		return nil, destErr
+	}
+
+	if options.ChunkSize < 0 {
+		return nil, jms20subset.CreateJMSException("Invalid ChunkSize specified: "+
+			strconv.Itoa(options.ChunkSize), "InvalidBatchOptions", nil)
+	}
+
+	results := make([]jms20subset.SendResult, len(msgs))
+	if len(msgs) == 0 {
+		return results, nil
+	}
+
+	settings, settingsErr := producer.getSendSettings(jms20subset.SendOptions{})
+	if settingsErr != nil {
+		return nil, settingsErr
+	}
+
+	// Every message in the batch is put to the same queue, which is the
+	// staging queue if the producer has a delivery delay.
+	destName := toQueueImpl(dest).uri()
+	queueName := settings.putQueueName(destName)
+
+	chunkSize := len(msgs)
+	if options.Transacted {
+		if options.ChunkSize > 0 && options.ChunkSize < chunkSize {
+			chunkSize = options.ChunkSize
+		}
+
+		// Only one unit of work can be in progress on a connection handle.
+		producer.ctx.conn.syncpointMutex.Lock()
+		defer producer.ctx.conn.syncpointMutex.Unlock()
+	}
+
+	// Open the queue once, and keep hold of the handle for the whole batch so
+	// that it isn't closed even if the context doesn't cache it.
+	handle, reused, err := producer.acquireQueue(queueName)
+	if err != nil {
+		producer.ctx.conn.noteError(err)
+		failure := createMQException(err)
+		for i := range results {
+			results[i].Err = failure
+		}
+		return results, failure
+	}
+	defer func() {
+		if handle != nil {
+			producer.ctx.handles.release(handle)
+		}
+	}()
+
+	var firstErr jms20subset.JMSException
+
+	for start := 0; start < len(msgs); start += chunkSize {
+
+		end := start + chunkSize
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+
+		var chunkErr error
+
+		for i := start; i < end && chunkErr == nil; i++ {
+
+			if handle == nil {
+				// The handle was discarded by an earlier put and could not be
+				// replaced, so try again for this message.
+				handle, reused, err = producer.acquireQueue(queueName)
+			}
+
+			var prepared *preparedMessage
+			if err == nil {
+				prepared, err = producer.prepareMessage(msgs[i], destName, settings)
+			}
+
+			if err == nil {
+				if options.Transacted {
+					prepared.pmo.Options &^= ibmmq.MQPMO_NO_SYNCPOINT
+					prepared.pmo.Options |= ibmmq.MQPMO_SYNCPOINT
+				}
+
+				handle, err = producer.putUsingHandle(queueName, handle, reused, prepared.mqmd,
+					prepared.pmo, prepared.buffer)
+				if err == nil {
+					prepared.markSent()
+				}
+				prepared.release()
+
+				// Any further message is put using a handle that has been used.
+				reused = true
 			}
 
-			// Set up this MQ message to contain the string from the JMS message.
-			putmqmd.Format = "MQSTR"
-			msgStr := typedMsg.GetText()
-			if msgStr != nil {
-				buffer = []byte(*msgStr)
+			if err != nil {
+				producer.ctx.conn.noteError(err)
+				results[i].Err = createMQException(err)
+				if firstErr == nil {
+					firstErr = results[i].Err
+				}
+				if options.Transacted {
+					chunkErr = err
+				}
+				err = nil
+				continue
 			}
 
-			// Store the Put MQMD so that we can later retrieve "out" fields like MsgId
-			typedMsg.mqmd = putmqmd
+			results[i].MessageID = msgs[i].GetJMSMessageID()
+		}
 
-		default:
-			// This "should never happen"(!) apart from in situations where we are
-			// part way through adding support for a new message type to this library.
-			log.Fatal(jms20subset.CreateJMSException("UnexpectedMessageType", "UnexpectedMessageType", nil))
+		if options.Transacted && chunkErr == nil {
+			chunkErr = producer.ctx.qMgr.Cmit()
+			if chunkErr != nil {
+				producer.ctx.conn.noteError(chunkErr)
+				if firstErr == nil {
+					firstErr = createMQException(chunkErr)
+				}
+			}
 		}
 
-		// If the producer has a TTL specified then apply it to the put MQMD so
-		// that MQ will honour it.
-		if producer.timeToLive > 0 {
-			// Note that JMS timeToLive in milliseconds, whereas MQMD Expiry expects
-			// 10ths of a second
-			putmqmd.Expiry = (int32(producer.timeToLive) / 100)
+		if chunkErr != nil {
+			// None of the messages in the chunk are delivered, so report the
+			// ones that were put successfully as failed too, along with any
+			// messages that were not attempted.
+			producer.ctx.qMgr.Back()
+
+			notSent := jms20subset.CreateJMSException("The message was not sent because an earlier "+
+				"message in the batch failed: "+firstErr.GetReason(), firstErr.GetErrorCode(), nil)
+			for i := start; i < len(msgs); i++ {
+				if results[i].Err == nil {
+					results[i] = jms20subset.SendResult{Err: notSent}
+				}
+			}
+			break
 		}
+	}
 
-		// Invoke the MQ command to put the message.
-		// Any Err that occurs will be handled below.
-		err = qObject.Put(putmqmd, pmo, buffer)
+	return results, firstErr
+}
 
+// GetDestination returns the destination that this producer was created for,
+// or nil if the destination is specified each time a message is sent.
+func (producer *ProducerImpl) GetDestination() jms20subset.Destination {
+	return producer.dest
+}
+
+// resolveDestination returns the destination that a message should be sent
+// to, taking into account the destination of the producer if it has one.
+func (producer *ProducerImpl) resolveDestination(dest jms20subset.Destination) (jms20subset.Destination, jms20subset.JMSException) {
+
+	if producer.dest == nil {
+		if dest == nil {
+			return nil, jms20subset.CreateJMSException("A destination must be specified for a producer "+
+				"that was created without a destination", "InvalidDestinationException", nil)
+		}
+		return dest, nil
 	}
 
-	// Note that the following block handles errors for both opening the queue
-	// and putting the message.
-	if err != nil {
+	// Equivalent to the Java JMS MessageProducer, which does not allow a
+	// message to be sent to a different destination. The same queue can be
+	// named with or without the name of the queue manager we are connected to.
+	if dest != nil && !producer.ctx.isSameQueue(toQueueImpl(dest), toQueueImpl(producer.dest)) {
+		return nil, jms20subset.CreateJMSException("Unable to send to "+dest.GetDestinationName()+
+			" using a producer that was created for "+producer.dest.GetDestinationName(),
+			"UnsupportedOperationException", nil)
+	}
+
+	return producer.dest, nil
+}
+
+// validateSendOptions checks that the options for a single message are valid.
+func validateSendOptions(options jms20subset.SendOptions) jms20subset.JMSException {
+
+	var problem string
+
+	switch {
+	case (options.DeliveryMode != 0 || options.DeliveryModeSet) &&
+		options.DeliveryMode != jms20subset.DeliveryMode_PERSISTENT &&
+		options.DeliveryMode != jms20subset.DeliveryMode_NON_PERSISTENT:
+		problem = "Invalid DeliveryMode specified: " + strconv.Itoa(options.DeliveryMode)
 
-		rcInt := int(err.(*ibmmq.MQReturn).MQRC)
-		errCode := strconv.Itoa(rcInt)
-		reason := ibmmq.MQItoString("RC", rcInt)
-		retErr = jms20subset.CreateJMSException(reason, errCode, err)
+	case options.TimeToLive < 0:
+		problem = "Invalid TimeToLive specified: " + strconv.Itoa(options.TimeToLive)
 
+	case options.Priority < 0 || options.Priority > 9:
+		problem = "Invalid Priority specified: " + strconv.Itoa(options.Priority)
+
+	case options.DeliveryDelay < 0:
+		problem = "Invalid DeliveryDelay specified: " + strconv.Itoa(options.DeliveryDelay)
+	}
+
+	if problem != "" {
+		return jms20subset.CreateJMSException(problem, "InvalidSendOptions", nil)
+	}
+
+	return nil
+}
+
+// sendSettings contains the message options that are used to send a message,
+// taken from the producer and the SendOptions for the message.
+type sendSettings struct {
+	deliveryMode  int
+	timeToLive    int
+	priority      int // Or MQPRI_PRIORITY_AS_Q_DEF to use the queue default
+	deliveryDelay int
+	delayQueue    string
+	defaults      messageDefaults
+	listener      jms20subset.CompletionListener
+}
+
+// getSendSettings takes a copy of the message options so that a concurrent
+// change to the producer doesn't affect a message while it is being sent, and
+// then applies any options that were specified for the message.
+func (producer *ProducerImpl) getSendSettings(options jms20subset.SendOptions) (sendSettings, jms20subset.JMSException) {
+
+	producer.mutex.Lock()
+	settings := sendSettings{
+		deliveryMode:  producer.deliveryMode,
+		timeToLive:    producer.timeToLive,
+		priority:      int(ibmmq.MQPRI_PRIORITY_AS_Q_DEF),
+		deliveryDelay: producer.deliveryDelay,
+		delayQueue:    producer.ctx.conn.cf.DeliveryDelayQueue,
+		defaults:      producer.defaults,
+		listener:      producer.listener,
 	}
+	producer.mutex.Unlock()
 
-	return retErr
+	if options.DeliveryMode != 0 || options.DeliveryModeSet {
+		settings.deliveryMode = options.DeliveryMode
+	}
+	if options.TimeToLive != 0 || options.TimeToLiveSet {
+		settings.timeToLive = options.TimeToLive
+	}
+	if options.Priority != 0 || options.PrioritySet {
+		settings.priority = options.Priority
+	}
+	if options.DeliveryDelay != 0 || options.DeliveryDelaySet {
+		settings.deliveryDelay = options.DeliveryDelay
+	}
 
+	// IBM MQ does not provide delayed delivery for client applications, so
+	// delayed messages are held on a staging queue until they are due.
+	if settings.deliveryDelay > 0 && settings.delayQueue == "" {
+		return settings, jms20subset.CreateJMSException("DeliveryDelay requires the DeliveryDelayQueue "+
+			"of the ConnectionFactory to be set", "UnsupportedDeliveryDelay", nil)
+	}
+
+	return settings, nil
+}
+
+// putQueueName returns the name of the queue that a message for the named
+// destination is put to, which is the staging queue if it is delayed.
+func (settings sendSettings) putQueueName(destName string) string {
+	if settings.deliveryDelay > 0 {
+		return settings.delayQueue
+	}
+	return destName
+}
+
+// preparedMessage contains the MQ structures that are used to put a message.
+type preparedMessage struct {
+	queueName string
+	mqmd      *ibmmq.MQMD
+	pmo       *ibmmq.MQPMO
+	buffer    []byte
+	msgHandle *ibmmq.MQMessageHandle // Set if the message has properties
+
+	// The message that is being sent, and the MQMD that it had beforehand.
+	msg    *TextMessageImpl
+	origMD *ibmmq.MQMD
+
+	// The delivery time of the message, or zero if it is not delayed.
+	deliveryTime int64
+}
+
+// release deletes the message handle that holds the properties of the message,
+// and stores the MQMD that was used to put the message on the message itself,
+// which must be done once the message has been put.
+//
+// The stored MQMD contains the fields that MQ sets when the message is put,
+// such as the MsgId and the put time, but keeps the message's own correlation
+// ID and reply destination, so that any defaults that were applied by the
+// producer are not stored on the message.
+func (prepared *preparedMessage) release() {
+	if prepared.msgHandle != nil {
+		prepared.msgHandle.DltMH(ibmmq.NewMQDMHO())
+	}
+
+	origMD := prepared.origMD
+	if origMD == nil {
+		origMD = ibmmq.NewMQMD()
+	}
+
+	sentMD := *prepared.mqmd
+	sentMD.CorrelId = origMD.CorrelId
+	sentMD.ReplyToQ = origMD.ReplyToQ
+	sentMD.ReplyToQMgr = origMD.ReplyToQMgr
+	prepared.msg.mqmd = &sentMD
+}
+
+// markSent records the delivery time on the message once it has been put
+// successfully, so that a message whose put failed keeps its previous value.
+func (prepared *preparedMessage) markSent() {
+	prepared.msg.deliveryTime = prepared.deliveryTime
+}
+
+// prepareMessage converts a message for the named destination into the MQ
+// structures that are used to put it, applying the message options in the
+// settings. The name is in the form that is returned by QueueImpl.uri, so that
+// it includes any queue manager name.
+func (producer *ProducerImpl) prepareMessage(msg jms20subset.Message, destName string,
+	settings sendSettings) (*preparedMessage, error) {
+
+	putmqmd := ibmmq.NewMQMD()
+	pmo := ibmmq.NewMQPMO()
+
+	// Configure the put message options, including asking MQ to allocate a
+	// unique message ID
+	pmo.Options = ibmmq.MQPMO_NO_SYNCPOINT | ibmmq.MQPMO_NEW_MSG_ID
+
+	var buffer []byte
+	var properties map[string]interface{}
+	var sentMsg *TextMessageImpl
+	var origMD *ibmmq.MQMD
+	var deliveryTime int64
+	headers := make(map[string]interface{})
+
+	// We have a "Message" object and can use a switch to safely convert it
+	// to the sub-types in order to convert it appropriately into an MQ message
+	// object.
+	switch typedMsg := msg.(type) {
+	case *TextMessageImpl:
+
+		// If the message already has an MQMD then start from a copy of it (for
+		// example it might contain ReplyTo information), so that the message
+		// itself is only updated once it has been put.
+		sentMsg, origMD = typedMsg, typedMsg.mqmd
+		if typedMsg.mqmd != nil {
+			mqmdCopy := *typedMsg.mqmd
+			putmqmd = &mqmdCopy
+		}
+
+		// Apply the header fields and properties that are set on the producer,
+		// unless the message already has a value for them.
+		var jmsType string
+		jmsType, properties = settings.defaults.apply(typedMsg, putmqmd)
+		if jmsType != "" {
+			headers[jmsTypePropertyName] = jmsType
+		}
+
+		// The delivery time is recorded on the message by markSent so that it
+		// can be read by the application after the message has been sent.
+		if settings.deliveryDelay > 0 {
+			deliveryTime = time.Now().UnixNano()/1000000 + int64(settings.deliveryDelay)
+			headers[deliveryTimePropertyName] = deliveryTime
+			headers[delayDestinationPropertyName] = destName
+		}
+
+		// Set up this MQ message to contain the string from the JMS message.
+		putmqmd.Format = "MQSTR"
+		msgStr := typedMsg.GetText()
+		if msgStr != nil {
+			buffer = []byte(*msgStr)
+		}
+
+		// Use the MsgId that was chosen by the application if there is one,
+		// instead of asking MQ to allocate a new one.
+		if typedMsg.msgId != nil {
+			putmqmd.MsgId = typedMsg.msgId
+			pmo.Options &^= ibmmq.MQPMO_NEW_MSG_ID
+		}
+
+	default:
+		// This "should never happen"(!) apart from in situations where we are
+		// part way through adding support for a new message type to this library.
+		log.Fatal(jms20subset.CreateJMSException("UnexpectedMessageType", "UnexpectedMessageType", nil))
+	}
+
+	// Convert the JMS persistence into the equivalent MQ message descriptor
+	// attribute. This is done after selecting the MQMD so that it also applies
+	// to a message that already has an MQMD.
+	if settings.deliveryMode == jms20subset.DeliveryMode_NON_PERSISTENT {
+		putmqmd.Persistence = ibmmq.MQPER_NOT_PERSISTENT
+	} else {
+		putmqmd.Persistence = ibmmq.MQPER_PERSISTENT
+	}
+
+	if settings.priority != int(ibmmq.MQPRI_PRIORITY_AS_Q_DEF) {
+		putmqmd.Priority = int32(settings.priority)
+	}
+
+	// If the producer has a TTL specified then apply it to the put MQMD so
+	// that MQ will honour it.
+	if settings.timeToLive > 0 {
+		// Note that JMS timeToLive in milliseconds, whereas MQMD Expiry expects
+		// 10ths of a second
+		putmqmd.Expiry = (int32(settings.timeToLive) / 100)
+	}
+
+	prepared := &preparedMessage{
+		queueName: settings.putQueueName(destName),
+		mqmd:      putmqmd,
+		pmo:       pmo,
+		buffer:    buffer,
+		msg:       sentMsg,
+		origMD:    origMD,
+
+		deliveryTime: deliveryTime,
+	}
+
+	// Message properties are sent using a message handle, which is only
+	// created if the message has any properties to avoid the extra calls.
+	if len(properties) > 0 || len(headers) > 0 {
+		msgHandle, err := createPropertiesHandle(producer.ctx.qMgr, properties, headers)
+		if err != nil {
+			return nil, err
+		}
+		prepared.msgHandle = &msgHandle
+		pmo.OriginalMsgHandle = msgHandle
+	}
+
+	return prepared, nil
+}
+
+// createMQException converts an error returned by an MQ call into a
+// JMSException.
+func createMQException(err error) jms20subset.JMSException {
+
+	rcInt := int(err.(*ibmmq.MQReturn).MQRC)
+	errCode := strconv.Itoa(rcInt)
+	reason := ibmmq.MQItoString("RC", rcInt)
+
+	return jms20subset.CreateJMSException(reason, errCode, err)
+}
+
+// putToQueue puts a message to the named queue, using the handle for the queue
+// that is cached by the context, or opening the queue if it isn't cached.
+func (producer *ProducerImpl) putToQueue(queueName string, putmqmd *ibmmq.MQMD,
+	pmo *ibmmq.MQPMO, buffer []byte) error {
+
+	handle, reused, err := producer.acquireQueue(queueName)
+	if err != nil {
+		return err
+	}
+
+	handle, err = producer.putUsingHandle(queueName, handle, reused, putmqmd, pmo, buffer)
+	if handle != nil {
+		producer.ctx.handles.release(handle)
+	}
+
+	return err
+}
+
+// acquireQueue returns a handle for the named queue, which must be released
+// once it is no longer being used. The returned bool is true if the handle was
+// already open, rather than having been opened by this call.
+//
+// The name can be in the URI form that includes a queue manager name, in which
+// case the queue is opened on that queue manager.
+func (producer *ProducerImpl) acquireQueue(queueName string) (*cachedHandle, bool, error) {
+
+	return producer.ctx.handles.acquire(queueName, func() (ibmmq.MQObject, error) {
+		mqod := parseQueueName(queueName).openDescriptor()
+
+		// Only open the queue for output, so that keeping it open doesn't
+		// prevent applications from opening the queue for exclusive input.
+		return producer.ctx.qMgr.Open(mqod, ibmmq.MQOO_OUTPUT|ibmmq.MQOO_FAIL_IF_QUIESCING)
+	})
+}
+
+// putUsingHandle puts a message using a handle that was returned by
+// acquireQueue.
+//
+// If a handle that was already open can no longer be used because the queue
+// has been deleted or changed since it was opened then the handle is
+// released, and the message is sent again using a newly opened handle. The
+// handle that the caller must release is returned, which is nil if the queue
+// could not be opened again.
+func (producer *ProducerImpl) putUsingHandle(queueName string, handle *cachedHandle, reused bool,
+	putmqmd *ibmmq.MQMD, pmo *ibmmq.MQPMO, buffer []byte) (*cachedHandle, error) {
+
+	for attempt := 1; ; attempt++ {
+
+		err := handle.qObject.Put(putmqmd, pmo, buffer)
+		if err == nil {
+			return handle, nil
+		}
+
+		switch err.(*ibmmq.MQReturn).MQRC {
+		case ibmmq.MQRC_OBJECT_CHANGED, ibmmq.MQRC_Q_DELETED, ibmmq.MQRC_HOBJ_ERROR:
+			producer.ctx.handles.invalidate(handle)
+
+			if reused && attempt == 1 {
+				producer.ctx.handles.release(handle)
+
+				var openErr error
+				handle, reused, openErr = producer.acquireQueue(queueName)
+				if openErr != nil {
+					return nil, openErr
+				}
+				continue
+			}
+
+		case ibmmq.MQRC_PUT_INHIBITED:
+			// The queue attributes have changed, so pick up the current
+			// definition when the queue is next used.
+			producer.ctx.handles.invalidate(handle)
+		}
+
+		return handle, err
+	}
 }
 
 // SetDeliveryMode contains the MQ logic necessary to store the specified
@@ -146,7 +674,9 @@ func (producer *ProducerImpl) SetDeliveryMode(mode int) jms20subset.JMSProducer
 	// Check that the specified mode parameter is one of the values that we permit,
 	// and if so store that value inside producer.
 	if mode == jms20subset.DeliveryMode_PERSISTENT || mode == jms20subset.DeliveryMode_NON_PERSISTENT {
+		producer.mutex.Lock()
 		producer.deliveryMode = mode
+		producer.mutex.Unlock()
 
 	} else {
 		// Normally we would throw an error here to indicate that an invalid value
@@ -162,6 +692,10 @@ func (producer *ProducerImpl) SetDeliveryMode(mode int) jms20subset.JMSProducer
 // GetDeliveryMode returns the current delivery mode that is set on this
 // Producer.
 func (producer *ProducerImpl) GetDeliveryMode() int {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
 	return producer.deliveryMode
 }
 
@@ -172,7 +706,9 @@ func (producer *ProducerImpl) SetTimeToLive(timeToLive int) jms20subset.JMSProdu
 
 	// Only accept a non-negative value for time to live.
 	if timeToLive >= 0 {
+		producer.mutex.Lock()
 		producer.timeToLive = timeToLive
+		producer.mutex.Unlock()
 
 	} else {
 		// Normally we would throw an error here to indicate that an invalid value
@@ -188,5 +724,289 @@ func (producer *ProducerImpl) SetTimeToLive(timeToLive int) jms20subset.JMSProdu
 // GetTimeToLive returns the current time to live that is set on this
 // Producer.
 func (producer *ProducerImpl) GetTimeToLive() int {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
 	return producer.timeToLive
 }
+
+// SetDeliveryDelay stores the delivery delay that is applied to messages
+// sent using this Producer.
+//
+// Messages with a delivery delay are sent to the DeliveryDelayQueue of the
+// ConnectionFactory, and moved to their destination by a DelayScheduler once
+// the delay has passed.
+func (producer *ProducerImpl) SetDeliveryDelay(deliveryDelay int) jms20subset.JMSProducer {
+
+	// Only accept a non-negative value for delivery delay.
+	if deliveryDelay >= 0 {
+		producer.mutex.Lock()
+		producer.deliveryDelay = deliveryDelay
+		producer.mutex.Unlock()
+
+	} else {
+		// Print the error instead of returning it, in order to support method
+		// chaining as for SetTimeToLive.
+		fmt.Println("Invalid DeliveryDelay specified: " + strconv.Itoa(deliveryDelay))
+	}
+
+	return producer
+}
+
+// GetDeliveryDelay returns the current delivery delay that is set on this
+// Producer.
+func (producer *ProducerImpl) GetDeliveryDelay() int {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	return producer.deliveryDelay
+}
+
+// SetDisableMessageID records the hint that the application does not need
+// message IDs.
+//
+// IBM MQ always gives each message a MsgId, and allocating it is not a
+// significant cost, so the hint is ignored as permitted by JMS and messages
+// have a message ID as normal.
+func (producer *ProducerImpl) SetDisableMessageID(value bool) jms20subset.JMSProducer {
+
+	producer.mutex.Lock()
+	producer.disableMessageID = value
+	producer.mutex.Unlock()
+
+	return producer
+}
+
+// GetDisableMessageID returns the hint that was set by SetDisableMessageID.
+func (producer *ProducerImpl) GetDisableMessageID() bool {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	return producer.disableMessageID
+}
+
+// SetDisableMessageTimestamp records the hint that the application does not
+// need message timestamps.
+//
+// IBM MQ always sets the PutDate and PutTime of a message, so the hint is
+// ignored as permitted by JMS and messages have a timestamp as normal.
+func (producer *ProducerImpl) SetDisableMessageTimestamp(value bool) jms20subset.JMSProducer {
+
+	producer.mutex.Lock()
+	producer.disableMessageTimestamp = value
+	producer.mutex.Unlock()
+
+	return producer
+}
+
+// GetDisableMessageTimestamp returns the hint that was set by
+// SetDisableMessageTimestamp.
+func (producer *ProducerImpl) GetDisableMessageTimestamp() bool {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	return producer.disableMessageTimestamp
+}
+
+// SetAsync makes the messages sent by this producer asynchronous, using the
+// MQPMO_ASYNC_RESPONSE put option so that Send returns without waiting for the
+// queue manager. The outcome is checked half a second after the first
+// unchecked send, after every 100 asynchronous sends and when the context is
+// closed, and is reported to the listener on the goroutine that checked it.
+// The listener can itself send further messages.
+//
+// The queue manager reports how many of the sends failed and the queue that
+// the first of them was sent to. If that does not show which messages failed,
+// for example when sends to several queues failed at once, then every message
+// sent since the previous check is reported to OnException.
+func (producer *ProducerImpl) SetAsync(listener jms20subset.CompletionListener) jms20subset.JMSProducer {
+
+	producer.mutex.Lock()
+	producer.listener = listener
+	producer.mutex.Unlock()
+
+	return producer
+}
+
+// GetAsync returns the CompletionListener of this Producer, or nil if it sends
+// messages synchronously.
+func (producer *ProducerImpl) GetAsync() jms20subset.CompletionListener {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	return producer.listener
+}
+
+// SetJMSCorrelationID stores a correlation ID that is applied to messages that
+// are sent by this producer, unless they have their own correlation ID.
+func (producer *ProducerImpl) SetJMSCorrelationID(correlID string) jms20subset.JMSProducer {
+
+	producer.mutex.Lock()
+	producer.defaults.correlationID = correlID
+	producer.mutex.Unlock()
+
+	return producer
+}
+
+// GetJMSCorrelationID returns the correlation ID that is set on this Producer.
+func (producer *ProducerImpl) GetJMSCorrelationID() string {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	return producer.defaults.correlationID
+}
+
+// SetJMSReplyTo stores a reply destination that is applied to messages that
+// are sent by this producer, unless they have their own reply destination.
+func (producer *ProducerImpl) SetJMSReplyTo(dest jms20subset.Destination) jms20subset.JMSProducer {
+
+	producer.mutex.Lock()
+	producer.defaults.replyTo = dest
+	producer.mutex.Unlock()
+
+	return producer
+}
+
+// GetJMSReplyTo returns the reply destination that is set on this Producer.
+func (producer *ProducerImpl) GetJMSReplyTo() jms20subset.Destination {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	return producer.defaults.replyTo
+}
+
+// SetJMSType stores a message type that is applied to messages that are sent
+// by this producer, unless they have their own type.
+func (producer *ProducerImpl) SetJMSType(jmsType string) jms20subset.JMSProducer {
+
+	producer.mutex.Lock()
+	producer.defaults.jmsType = jmsType
+	producer.mutex.Unlock()
+
+	return producer
+}
+
+// GetJMSType returns the message type that is set on this Producer.
+func (producer *ProducerImpl) GetJMSType() string {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	return producer.defaults.jmsType
+}
+
+// SetStringProperty stores a string property that is applied to messages that
+// are sent by this producer.
+func (producer *ProducerImpl) SetStringProperty(name string, value string) jms20subset.JMSProducer {
+	return producer.setProperty(name, value)
+}
+
+// SetIntProperty stores an integer property that is applied to messages that
+// are sent by this producer.
+func (producer *ProducerImpl) SetIntProperty(name string, value int) jms20subset.JMSProducer {
+	return producer.setProperty(name, value)
+}
+
+// SetBooleanProperty stores a boolean property that is applied to messages that
+// are sent by this producer.
+func (producer *ProducerImpl) SetBooleanProperty(name string, value bool) jms20subset.JMSProducer {
+	return producer.setProperty(name, value)
+}
+
+// SetDoubleProperty stores a floating point property that is applied to
+// messages that are sent by this producer.
+func (producer *ProducerImpl) SetDoubleProperty(name string, value float64) jms20subset.JMSProducer {
+	return producer.setProperty(name, value)
+}
+
+// GetPropertyNames returns the names of the properties that are set on this
+// Producer, in alphabetical order.
+func (producer *ProducerImpl) GetPropertyNames() []string {
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	return sortedPropertyNames(producer.defaults.properties)
+}
+
+// ClearProperties removes the properties that are set on this Producer.
+func (producer *ProducerImpl) ClearProperties() jms20subset.JMSProducer {
+
+	producer.mutex.Lock()
+	producer.defaults.properties = nil
+	producer.mutex.Unlock()
+
+	return producer
+}
+
+// setProperty stores a property that is applied to messages that are sent by
+// this producer.
+func (producer *ProducerImpl) setProperty(name string, value interface{}) jms20subset.JMSProducer {
+
+	if nameErr := validatePropertyName(name); nameErr != nil {
+		// Normally we would throw an error here to indicate that an invalid value
+		// was specified, however we have decided that it is more useful to support
+		// method chaining, which prevents us from returning an error object.
+		// Instead we settle for printing an error message to the console.
+		fmt.Println(nameErr.GetReason())
+		return producer
+	}
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	// Replace the map rather than changing it, so that messages that are being
+	// sent by other goroutines keep the properties that they started with.
+	properties := make(map[string]interface{}, len(producer.defaults.properties)+1)
+	for propName, propValue := range producer.defaults.properties {
+		properties[propName] = propValue
+	}
+	properties[name] = value
+	producer.defaults.properties = properties
+
+	return producer
+}
+
+// apply works out the header fields and properties of a message that is
+// about to be sent, by adding those of the producer to the ones that the
+// message has. The correlation ID and reply destination are set in the MQMD
+// that is used to put the message, and the JMSType and properties to send are
+// returned, so that the message itself is not changed.
+func (defaults messageDefaults) apply(msg *TextMessageImpl, putmqmd *ibmmq.MQMD) (string, map[string]interface{}) {
+
+	if defaults.correlationID != "" && msg.GetJMSCorrelationID() == "" {
+		putmqmd.CorrelId = convertStringToMQBytes(defaults.correlationID)
+	}
+
+	if defaults.replyTo != nil && msg.GetJMSReplyTo() == nil {
+		replyTo := toQueueImpl(defaults.replyTo)
+		putmqmd.ReplyToQ = replyTo.queueName
+		putmqmd.ReplyToQMgr = replyTo.queueManagerName
+	}
+
+	jmsType := msg.jmsType
+	if jmsType == "" {
+		jmsType = defaults.jmsType
+	}
+
+	if len(defaults.properties) == 0 {
+		return jmsType, msg.properties
+	}
+
+	properties := make(map[string]interface{}, len(msg.properties)+len(defaults.properties))
+	for name, value := range defaults.properties {
+		properties[name] = value
+	}
+	for name, value := range msg.properties {
+		properties[name] = value
+	}
+
+	return jmsType, properties
+}
@@ -9,10 +9,89 @@
 //
 package mqjms
 
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang/ibmmq"
+	"strings"
+)
+
+// The prefix of the URI form of a queue name, which can include the name of
+// the queue manager that the queue belongs to, in the same way as for the IBM
+// MQ classes for JMS; for example "queue://QM2/APP.REQUEST".
+const queueURIPrefix = "queue://"
+
 // QueueImpl encapsulates the provider-specific attributes necessary to
 // communicate with an IBM MQ queue.
+//
+// If a queue manager name is set then messages are sent to the queue on that
+// queue manager, which can be a remote queue manager that is reached using a
+// transmission queue.
 type QueueImpl struct {
-	queueName string
+	queueName        string
+	queueManagerName string
+}
+
+// parseQueueName creates a QueueImpl from either a plain queue name or the URI
+// form of the name, which includes the queue manager name.
+func parseQueueName(name string) QueueImpl {
+
+	if !strings.HasPrefix(name, queueURIPrefix) {
+		return QueueImpl{queueName: name}
+	}
+
+	// A URI without a queue manager name such as "queue:///Q1" refers to a
+	// queue on the queue manager that the application is connected to.
+	path := strings.TrimPrefix(name, queueURIPrefix)
+	if slash := strings.Index(path, "/"); slash >= 0 {
+		return QueueImpl{
+			queueName:        path[slash+1:],
+			queueManagerName: path[:slash],
+		}
+	}
+
+	return QueueImpl{queueName: path}
+}
+
+// toQueueImpl returns the QueueImpl for a destination, which is created from
+// the name of the destination if it was not created by this package.
+func toQueueImpl(dest jms20subset.Destination) QueueImpl {
+
+	if queue, ok := dest.(QueueImpl); ok {
+		return queue
+	}
+
+	return parseQueueName(dest.GetDestinationName())
+}
+
+// GetQueueManagerName returns the name of the queue manager that the queue
+// belongs to, or an empty string if it belongs to the queue manager that the
+// application is connected to.
+func (queue QueueImpl) GetQueueManagerName() string {
+
+	return queue.queueManagerName
+
+}
+
+// uri returns a string that identifies both the queue and its queue manager,
+// which is just the queue name if there is no queue manager name.
+func (queue QueueImpl) uri() string {
+
+	if queue.queueManagerName == "" {
+		return queue.queueName
+	}
+
+	return queueURIPrefix + queue.queueManagerName + "/" + queue.queueName
+}
+
+// openDescriptor returns an object descriptor that is used to open the queue.
+func (queue QueueImpl) openDescriptor() *ibmmq.MQOD {
+
+	mqod := ibmmq.NewMQOD()
+	mqod.ObjectType = ibmmq.MQOT_Q
+	mqod.ObjectName = queue.queueName
+	mqod.ObjectQMgrName = queue.queueManagerName
+
+	return mqod
 }
 
 // GetQueueName returns the provider-specific name of the queue that is
@@ -23,8 +23,12 @@ import (
 // TextMessageImpl contains the IBM MQ specific attributes necessary to
 // present a message that carries a string.
 type TextMessageImpl struct {
-	bodyStr *string
-	mqmd    *ibmmq.MQMD
+	bodyStr      *string
+	mqmd         *ibmmq.MQMD
+	jmsType      string
+	deliveryTime int64  // Set if the message was sent with a delivery delay
+	msgId        []byte // Set if the application has chosen the MsgId
+	properties   map[string]interface{}
 }
 
 // GetText returns the string that is contained in this TextMessage.
@@ -77,6 +81,49 @@ func (msg *TextMessageImpl) GetJMSMessageID() string {
 	return msgIdStr
 }
 
+// SetMQMsgId sets the MQ MsgId that this message is sent with, instead of
+// the unique MsgId that is normally allocated by the queue manager, for
+// example so that a partner system can detect duplicate messages. The MsgId
+// can be up to 24 bytes long, and is padded with zero bytes.
+//
+// The application is responsible for making sure that the MsgId is unique.
+// Once the message has been sent GetJMSMessageID returns the hex encoding of
+// the padded MsgId, in the same way as for a MsgId allocated by MQ.
+func (msg *TextMessageImpl) SetMQMsgId(msgId []byte) jms20subset.JMSException {
+
+	if len(msgId) > int(ibmmq.MQ_MSG_ID_LENGTH) {
+		return jms20subset.CreateJMSException("MsgId must not be longer than "+
+			strconv.Itoa(int(ibmmq.MQ_MSG_ID_LENGTH))+" bytes", "InvalidMsgId", nil)
+	}
+
+	if msgId == nil {
+		// Go back to using a MsgId allocated by MQ.
+		msg.msgId = nil
+		return nil
+	}
+
+	msg.msgId = make([]byte, ibmmq.MQ_MSG_ID_LENGTH)
+	copy(msg.msgId, msgId)
+
+	return nil
+}
+
+// GetMQMsgId returns the MQ MsgId of this message, which is the MsgId that
+// was set by the application or allocated by MQ when the message was sent, or
+// the MsgId of a received message.
+func (msg *TextMessageImpl) GetMQMsgId() []byte {
+
+	if msg.msgId != nil {
+		return msg.msgId
+	}
+
+	if msg.mqmd != nil {
+		return msg.mqmd.MsgId
+	}
+
+	return nil
+}
+
 // SetJMSReplyTo uses the specified Destination object to configure the reply
 // attributes of the native MQ message fields.
 func (msg *TextMessageImpl) SetJMSReplyTo(dest jms20subset.Destination) jms20subset.JMSException {
@@ -91,7 +138,11 @@ func (msg *TextMessageImpl) SetJMSReplyTo(dest jms20subset.Destination) jms20sub
 		}
 
 		// Save the queue information into the MQMD so that it can be transmitted.
+		// If no queue manager is specified then MQ fills in the name of the
+		// queue manager that the message is put to, so that replies are routed
+		// back to it.
 		msg.mqmd.ReplyToQ = typedDest.queueName
+		msg.mqmd.ReplyToQMgr = typedDest.queueManagerName
 
 	default:
 		// This "should never happen"(!) apart from in situations where we are
@@ -114,10 +165,14 @@ func (msg *TextMessageImpl) GetJMSReplyTo() jms20subset.Destination {
 	// destination.
 	if msg.mqmd != nil && msg.mqmd.ReplyToQ != "" {
 		replyQ := strings.TrimSpace(msg.mqmd.ReplyToQ)
+		replyQMgr := strings.TrimSpace(msg.mqmd.ReplyToQMgr)
 
-		// Create the Destination object and populate it to be returned.
+		// Create the Destination object and populate it to be returned. The
+		// queue manager name means that a reply is routed back to the queue
+		// manager that the message came from.
 		replyDest = QueueImpl{
-			queueName: replyQ,
+			queueName:        replyQ,
+			queueManagerName: replyQMgr,
 		}
 	}
 
@@ -273,3 +328,131 @@ func (msg *TextMessageImpl) GetJMSTimestamp() int64 {
 
 	return timestamp
 }
+
+// GetJMSDeliveryTime returns the earliest time at which this message can be
+// delivered, in milliseconds since the epoch. This is the JMSTimestamp of a
+// message that was sent without a delivery delay.
+func (msg *TextMessageImpl) GetJMSDeliveryTime() int64 {
+
+	if msg.deliveryTime != 0 {
+		return msg.deliveryTime
+	}
+
+	return msg.GetJMSTimestamp()
+}
+
+// setHeaders populates the header fields of a received message from the
+// header properties that were sent with it.
+func (msg *TextMessageImpl) setHeaders(headers map[string]interface{}) {
+
+	if value, ok := headers[jmsTypePropertyName]; ok {
+		msg.jmsType = propertyToString(value)
+	}
+
+	if value, ok := headers[deliveryTimePropertyName]; ok {
+		if deliveryTime, err := propertyToInt(deliveryTimePropertyName, value); err == nil {
+			msg.deliveryTime = int64(deliveryTime)
+		}
+	}
+}
+
+// SetJMSType stores the type of this message, which is sent as the mcd.Type
+// message property in the same way as the IBM MQ classes for JMS.
+func (msg *TextMessageImpl) SetJMSType(jmsType string) jms20subset.JMSException {
+	msg.jmsType = jmsType
+	return nil
+}
+
+// GetJMSType returns the type of this message.
+func (msg *TextMessageImpl) GetJMSType() string {
+	return msg.jmsType
+}
+
+// SetStringProperty stores a string application property, which is sent as an
+// MQ message property.
+func (msg *TextMessageImpl) SetStringProperty(name string, value string) jms20subset.JMSException {
+	return msg.setProperty(name, value)
+}
+
+// GetStringProperty returns an application property of this message as a
+// string.
+func (msg *TextMessageImpl) GetStringProperty(name string) *string {
+
+	value, ok := msg.properties[name]
+	if !ok {
+		return nil
+	}
+
+	strValue := propertyToString(value)
+	return &strValue
+}
+
+// SetIntProperty stores an integer application property, which is sent as an
+// MQ message property.
+func (msg *TextMessageImpl) SetIntProperty(name string, value int) jms20subset.JMSException {
+	return msg.setProperty(name, value)
+}
+
+// GetIntProperty returns an application property of this message as an
+// integer.
+func (msg *TextMessageImpl) GetIntProperty(name string) (int, jms20subset.JMSException) {
+	return propertyToInt(name, msg.properties[name])
+}
+
+// SetBooleanProperty stores a boolean application property, which is sent as
+// an MQ message property.
+func (msg *TextMessageImpl) SetBooleanProperty(name string, value bool) jms20subset.JMSException {
+	return msg.setProperty(name, value)
+}
+
+// GetBooleanProperty returns an application property of this message as a
+// boolean.
+func (msg *TextMessageImpl) GetBooleanProperty(name string) (bool, jms20subset.JMSException) {
+	return propertyToBool(name, msg.properties[name])
+}
+
+// SetDoubleProperty stores a floating point application property, which is
+// sent as an MQ message property.
+func (msg *TextMessageImpl) SetDoubleProperty(name string, value float64) jms20subset.JMSException {
+	return msg.setProperty(name, value)
+}
+
+// GetDoubleProperty returns an application property of this message as a
+// floating point number.
+func (msg *TextMessageImpl) GetDoubleProperty(name string) (float64, jms20subset.JMSException) {
+	return propertyToFloat(name, msg.properties[name])
+}
+
+// PropertyExists returns true if this message has the named application
+// property.
+func (msg *TextMessageImpl) PropertyExists(name string) bool {
+	_, ok := msg.properties[name]
+	return ok
+}
+
+// GetPropertyNames returns the names of the application properties of this
+// message, in alphabetical order.
+func (msg *TextMessageImpl) GetPropertyNames() []string {
+	return sortedPropertyNames(msg.properties)
+}
+
+// ClearProperties removes the application properties of this message.
+func (msg *TextMessageImpl) ClearProperties() jms20subset.JMSException {
+	msg.properties = nil
+	return nil
+}
+
+// setProperty stores an application property after checking its name.
+func (msg *TextMessageImpl) setProperty(name string, value interface{}) jms20subset.JMSException {
+
+	if nameErr := validatePropertyName(name); nameErr != nil {
+		return nameErr
+	}
+
+	if msg.properties == nil {
+		msg.properties = make(map[string]interface{})
+	}
+	msg.properties[name] = value
+
+	return nil
+}
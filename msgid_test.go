@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"encoding/hex"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test sending a message with a MsgId that is chosen by the application, and
+ * that the hints to disable message IDs and timestamps are accepted.
+ */
+func TestApplicationMsgId(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	producer := context.CreateProducer().
+		SetDisableMessageID(true).
+		SetDisableMessageTimestamp(true)
+	assert.True(t, producer.GetDisableMessageID())
+	assert.True(t, producer.GetDisableMessageTimestamp())
+
+	msgId := []byte("partner-0001")
+	expectedId := hex.EncodeToString(append(msgId, make([]byte, 24-len(msgId))...))
+
+	msg := context.CreateTextMessageWithString("Sent with an application MsgId").(*mqjms.TextMessageImpl)
+	assert.Nil(t, msg.SetMQMsgId(msgId))
+
+	errSend := producer.Send(queue, msg)
+	assert.Nil(t, errSend)
+	assert.Equal(t, expectedId, msg.GetJMSMessageID())
+
+	// The hints are ignored, so a message without its own MsgId still has one.
+	plainMsg := context.CreateTextMessageWithString("Sent with an MQ MsgId")
+	errSend = producer.Send(queue, plainMsg)
+	assert.Nil(t, errSend)
+	assert.NotEqual(t, "", plainMsg.GetJMSMessageID())
+	assert.NotEqual(t, int64(0), plainMsg.GetJMSTimestamp())
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvMsg, rcvErr := consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	if rcvMsg != nil {
+		assert.Equal(t, expectedId, rcvMsg.GetJMSMessageID())
+	}
+
+	rcvMsg, rcvErr = consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	if rcvMsg != nil {
+		assert.Equal(t, plainMsg.GetJMSMessageID(), rcvMsg.GetJMSMessageID())
+	}
+
+}
+
+/*
+ * Test that a MsgId that is too long is rejected.
+ */
+func TestApplicationMsgIdInvalid(t *testing.T) {
+
+	msg := &mqjms.TextMessageImpl{}
+
+	err := msg.SetMQMsgId(make([]byte, 25))
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Equal(t, "InvalidMsgId", err.GetErrorCode())
+	}
+
+	assert.Nil(t, msg.SetMQMsgId([]byte{1, 2, 3}))
+	assert.Equal(t, 24, len(msg.GetMQMsgId()))
+	assert.Equal(t, []byte{1, 2, 3}, msg.GetMQMsgId()[:3])
+
+}
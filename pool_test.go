@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+/*
+ * Test that contexts obtained from a PooledConnectionFactory can be used to
+ * send and receive messages, and are reused after they have been closed.
+ */
+func TestPooledConnectionFactory(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	pool := &mqjms.PooledConnectionFactory{
+		Factory:      cf,
+		MaxActive:    2,
+		TestOnBorrow: true,
+	}
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		context, ctxErr := pool.CreateContext()
+		assert.Nil(t, ctxErr)
+		if context == nil {
+			return
+		}
+
+		queue := context.CreateQueue("DEV.QUEUE.1")
+		errSend := context.CreateProducer().SendString(queue, "Sent using a pooled context")
+		assert.Nil(t, errSend)
+
+		consumer, conErr := context.CreateConsumer(queue)
+		assert.Nil(t, conErr)
+
+		rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+		assert.Nil(t, rcvErr)
+		assert.NotNil(t, rcvBody)
+
+		// Also closes the consumer.
+		context.Close()
+	}
+
+	// Only one connection was made to the queue manager.
+	stats := pool.Stats()
+	assert.Equal(t, 1, stats.Created)
+	assert.Equal(t, 3, stats.Borrowed)
+	assert.Equal(t, 2, stats.Reused)
+	assert.Equal(t, 0, stats.Active)
+	assert.Equal(t, 1, stats.Idle)
+
+}
+
+/*
+ * Test the limits of a PooledConnectionFactory, using a ConnectionFactory that
+ * does not connect to a queue manager.
+ */
+func TestPooledConnectionFactoryLimits(t *testing.T) {
+
+	fake := &fakeConnectionFactory{}
+	pool := &mqjms.PooledConnectionFactory{
+		Factory:   fake,
+		MaxActive: 2,
+		MaxIdle:   1,
+	}
+
+	ctx1, err := pool.CreateContext()
+	assert.Nil(t, err)
+	ctx2, err := pool.CreateContext()
+	assert.Nil(t, err)
+
+	// Both of the contexts are in use, so there are none left.
+	ctx3, err := pool.CreateContext()
+	assert.Nil(t, ctx3)
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Equal(t, mqjms.ErrorCode_POOL_EXHAUSTED, err.GetErrorCode())
+	}
+
+	// A context is handed out when one is returned within the MaxWait time.
+	pool.MaxWait = 5000
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ctx1.Close()
+	}()
+	ctx3, err = pool.CreateContext()
+	assert.Nil(t, err)
+	assert.NotNil(t, ctx3)
+
+	// Closing a context more than once has no effect.
+	ctx1.Close()
+
+	// Only one of the returned contexts is kept.
+	ctx2.Close()
+	ctx3.Close()
+
+	stats := pool.Stats()
+	assert.Equal(t, 2, stats.Created)
+	assert.Equal(t, 1, stats.Destroyed)
+	assert.Equal(t, 3, stats.Borrowed)
+	assert.Equal(t, 1, stats.Reused)
+	assert.Equal(t, 1, stats.Waited)
+	assert.Equal(t, 1, stats.Exhausted)
+	assert.Equal(t, 0, stats.Active)
+	assert.Equal(t, 1, stats.Idle)
+	assert.Equal(t, 1, fake.closed)
+
+	// A closed context can no longer be used.
+	_, err = ctx3.CreateConsumer(ctx3.CreateQueue("DEV.QUEUE.1"))
+	assert.NotNil(t, err)
+
+	// Closing the pool closes the idle contexts.
+	pool.Close()
+	assert.Equal(t, 2, fake.closed)
+	_, err = pool.CreateContext()
+	assert.NotNil(t, err)
+
+}
+
+/*
+ * Test that contexts are not reused after their MaxLifetime.
+ */
+func TestPooledConnectionFactoryLifetime(t *testing.T) {
+
+	fake := &fakeConnectionFactory{}
+	pool := &mqjms.PooledConnectionFactory{
+		Factory:     fake,
+		MaxLifetime: 1,
+	}
+	defer pool.Close()
+
+	ctx, err := pool.CreateContext()
+	assert.Nil(t, err)
+	ctx.Close()
+
+	time.Sleep(1100 * time.Millisecond)
+
+	ctx, err = pool.CreateContext()
+	assert.Nil(t, err)
+	ctx.Close()
+
+	stats := pool.Stats()
+	assert.Equal(t, 2, stats.Created)
+	assert.Equal(t, 0, stats.Reused)
+	assert.Equal(t, 1, stats.Destroyed)
+
+}
+
+/*
+ * Test that a producer created from a pooled context cannot be used once the
+ * context has been returned to the pool.
+ */
+func TestPooledProducerAfterClose(t *testing.T) {
+
+	fake := &fakeConnectionFactory{}
+	pool := &mqjms.PooledConnectionFactory{Factory: fake}
+	defer pool.Close()
+
+	ctx, err := pool.CreateContext()
+	assert.Nil(t, err)
+
+	queue := ctx.CreateQueue("DEV.QUEUE.1")
+	producer := ctx.CreateProducer().SetJMSType("test")
+	assert.Nil(t, producer.SendString(queue, "Message sent while the context is borrowed"))
+	assert.Equal(t, 1, fake.sent)
+
+	ctx.Close()
+
+	sendErr := producer.SendString(queue, "Message sent after returning the context")
+	assert.NotNil(t, sendErr)
+	assert.Equal(t, "IllegalStateException", sendErr.GetErrorCode())
+	assert.Equal(t, 1, fake.sent)
+
+	assert.Nil(t, ctx.CreateQueue("DEV.QUEUE.1"))
+
+}
+
+// fakeConnectionFactory creates contexts that count how often they have been
+// closed, without connecting to a queue manager.
+type fakeConnectionFactory struct {
+	closed int
+	sent   int
+}
+
+func (cf *fakeConnectionFactory) CreateContext() (jms20subset.JMSContext, jms20subset.JMSException) {
+	return &fakeContext{cf: cf}, nil
+}
+
+// fakeContext is a JMSContext that is only able to create queue and producer
+// objects.
+type fakeContext struct {
+	jms20subset.JMSContext
+	cf *fakeConnectionFactory
+}
+
+func (ctx *fakeContext) CreateQueue(queueName string) jms20subset.Queue {
+	return mqjms.QueueImpl{}
+}
+
+func (ctx *fakeContext) SetExceptionListener(listener func(jms20subset.JMSException)) jms20subset.JMSException {
+	return nil
+}
+
+func (ctx *fakeContext) Close() {
+	ctx.cf.closed++
+}
+
+func (ctx *fakeContext) CreateProducer() jms20subset.JMSProducer {
+	return &fakeProducer{cf: ctx.cf}
+}
+
+// fakeProducer is a JMSProducer that counts the messages sent using it.
+type fakeProducer struct {
+	jms20subset.JMSProducer
+	cf *fakeConnectionFactory
+}
+
+func (producer *fakeProducer) SetJMSType(jmsType string) jms20subset.JMSProducer {
+	return producer
+}
+
+func (producer *fakeProducer) SendString(dest jms20subset.Destination, body string) jms20subset.JMSException {
+	producer.cf.sent++
+	return nil
+}
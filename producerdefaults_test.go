@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test that the header fields and properties that are set on a producer are
+ * applied to the messages that it sends, unless the message has its own value.
+ */
+func TestProducerDefaults(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	replyQueue := context.CreateQueue("DEV.QUEUE.2")
+
+	producer := context.CreateProducer().
+		SetJMSCorrelationID("producerCorrel").
+		SetJMSReplyTo(replyQueue).
+		SetJMSType("order").
+		SetStringProperty("region", "eu").
+		SetIntProperty("attempt", 1).
+		SetBooleanProperty("urgent", false).
+		SetDoubleProperty("amount", 12.5)
+
+	assert.Equal(t, "producerCorrel", producer.GetJMSCorrelationID())
+	assert.Equal(t, replyQueue, producer.GetJMSReplyTo())
+	assert.Equal(t, "order", producer.GetJMSType())
+	assert.Equal(t, []string{"amount", "attempt", "region", "urgent"}, producer.GetPropertyNames())
+
+	// The producer values are used for a string message.
+	errSend := producer.SendString(queue, "Sent with the producer defaults")
+	assert.Nil(t, errSend)
+
+	// Values that are set on the message take precedence.
+	msg := context.CreateTextMessageWithString("Sent with message values")
+	msg.SetJMSCorrelationID("messageCorrel")
+	msg.SetJMSType("refund")
+	msg.SetIntProperty("attempt", 2)
+	errSend = producer.Send(queue, msg)
+	assert.Nil(t, errSend)
+
+	// The producer values are not stored on the message that was sent, apart
+	// from the fields that are set by sending it.
+	assert.Equal(t, "messageCorrel", msg.GetJMSCorrelationID())
+	assert.Nil(t, msg.GetJMSReplyTo())
+	assert.False(t, msg.PropertyExists("region"))
+	assert.NotEqual(t, "", msg.GetJMSMessageID())
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvMsg, rcvErr := consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	assert.Equal(t, "producerCorrel", rcvMsg.GetJMSCorrelationID())
+	assert.Equal(t, "DEV.QUEUE.2", rcvMsg.GetJMSReplyTo().GetDestinationName())
+	assert.Equal(t, "order", rcvMsg.GetJMSType())
+	assert.Equal(t, "eu", *rcvMsg.GetStringProperty("region"))
+	attempt, propErr := rcvMsg.GetIntProperty("attempt")
+	assert.Nil(t, propErr)
+	assert.Equal(t, 1, attempt)
+	urgent, propErr := rcvMsg.GetBooleanProperty("urgent")
+	assert.Nil(t, propErr)
+	assert.False(t, urgent)
+	amount, propErr := rcvMsg.GetDoubleProperty("amount")
+	assert.Nil(t, propErr)
+	assert.Equal(t, 12.5, amount)
+
+	rcvMsg, rcvErr = consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	assert.Equal(t, "messageCorrel", rcvMsg.GetJMSCorrelationID())
+	assert.Equal(t, "DEV.QUEUE.2", rcvMsg.GetJMSReplyTo().GetDestinationName())
+	assert.Equal(t, "refund", rcvMsg.GetJMSType())
+	assert.Equal(t, "eu", *rcvMsg.GetStringProperty("region"))
+	attempt, propErr = rcvMsg.GetIntProperty("attempt")
+	assert.Nil(t, propErr)
+	assert.Equal(t, 2, attempt)
+
+	// A property can be read as a string, but not as an unrelated type.
+	assert.Equal(t, "2", *rcvMsg.GetStringProperty("attempt"))
+	_, propErr = rcvMsg.GetIntProperty("region")
+	assert.NotNil(t, propErr)
+	assert.Nil(t, rcvMsg.GetStringProperty("missing"))
+
+	// The consumer reuses its message handle, so check that a message without
+	// properties does not pick up those of the previous message.
+	errSend = context.CreateProducer().SendString(queue, "Sent without properties")
+	assert.Nil(t, errSend)
+	rcvMsg, rcvErr = consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	assert.Empty(t, rcvMsg.GetPropertyNames())
+
+}
+
+/*
+ * Test the properties of a message that has not been sent.
+ */
+func TestMessageProperties(t *testing.T) {
+
+	msg := &mqjms.TextMessageImpl{}
+
+	assert.Nil(t, msg.SetStringProperty("name", "value"))
+	assert.Nil(t, msg.SetIntProperty("count", 3))
+	assert.True(t, msg.PropertyExists("name"))
+	assert.Equal(t, []string{"count", "name"}, msg.GetPropertyNames())
+
+	count, err := msg.GetIntProperty("count")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	// Missing properties.
+	_, err = msg.GetIntProperty("missing")
+	assert.NotNil(t, err)
+	missingBool, err := msg.GetBooleanProperty("missing")
+	assert.Nil(t, err)
+	assert.False(t, missingBool)
+
+	// Names in the folders used for JMS header fields are rejected.
+	err = msg.SetStringProperty("mcd.Type", "value")
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Equal(t, "InvalidPropertyName", err.GetErrorCode())
+	}
+	assert.NotNil(t, msg.SetStringProperty("", "value"))
+
+	assert.Nil(t, msg.ClearProperties())
+	assert.False(t, msg.PropertyExists("name"))
+	assert.Empty(t, msg.GetPropertyNames())
+
+}
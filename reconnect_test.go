@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+)
+
+/*
+ * Test that a connection can be created with automatic client reconnection
+ * enabled, and that the reconnection events can be observed using an
+ * exception listener.
+ *
+ * To see the reconnection behaviour in action, restart the queue manager
+ * (for example using "endmqm -r") while the application is running.
+ */
+func TestClientReconnect(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	// Ask for the connection to be reconnected automatically, and to give up
+	// if that has not succeeded within a minute.
+	cf.ClientReconnectOptions = mqjms.ClientReconnect_RECONNECT
+	cf.ClientReconnectTimeout = 60
+
+	// Creates a connection to the queue manager, using defer to close it automatically
+	// at the end of the function (if it was created successfully)
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	// Reconnection events such as MQRC_RECONNECTING and MQRC_RECONNECTED are
+	// passed to the exception listener.
+	listenerErr := context.SetExceptionListener(func(ex jms20subset.JMSException) {
+		log.Print("Connection event: " + ex.GetReason())
+	})
+	assert.Nil(t, listenerErr)
+
+	// Check that messages can be sent and received as normal.
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	producer := context.CreateProducer()
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	msgBody := "Reconnectable message"
+	errSend := producer.SendString(queue, msgBody)
+	assert.Nil(t, errSend)
+
+	rcvBody, rcvErr := consumer.ReceiveStringBody(1000)
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvBody)
+	assert.Equal(t, msgBody, *rcvBody)
+
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test sending a message to a queue that is identified by its queue manager,
+ * and that the reply destination of a message includes its queue manager so
+ * that replies are routed back to it.
+ */
+func TestSendToQueueManager(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	// Name the queue manager that we are connected to, so that the message
+	// arrives without needing a transmission queue to be defined.
+	queue := context.CreateQueue("queue://" + cf.QMName + "/DEV.QUEUE.1")
+	assert.Equal(t, "DEV.QUEUE.1", queue.GetQueueName())
+
+	msg := context.CreateTextMessageWithString("Sent to a named queue manager")
+	msg.SetJMSReplyTo(context.CreateQueue("queue://REMOTEQM/APP.REPLY"))
+	errSend := context.CreateProducer().Send(queue, msg)
+	assert.Nil(t, errSend)
+
+	// A reply queue without a queue manager is given the local queue manager.
+	errSend = context.CreateProducer().
+		SetJMSReplyTo(context.CreateQueue("DEV.QUEUE.2")).
+		SendString(queue, "Sent with a local reply queue")
+	assert.Nil(t, errSend)
+
+	consumer, conErr := context.CreateConsumer(context.CreateQueue("DEV.QUEUE.1"))
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvMsg, rcvErr := consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	if rcvMsg != nil {
+		replyQueue, ok := rcvMsg.GetJMSReplyTo().(mqjms.QueueImpl)
+		assert.True(t, ok)
+		assert.Equal(t, "APP.REPLY", replyQueue.GetQueueName())
+		assert.Equal(t, "REMOTEQM", replyQueue.GetQueueManagerName())
+	}
+
+	rcvMsg, rcvErr = consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	if rcvMsg != nil {
+		replyQueue, ok := rcvMsg.GetJMSReplyTo().(mqjms.QueueImpl)
+		assert.True(t, ok)
+		assert.Equal(t, "DEV.QUEUE.2", replyQueue.GetQueueName())
+		assert.Equal(t, cf.QMName, replyQueue.GetQueueManagerName())
+	}
+
+}
+
+/*
+ * Test that a producer created for a queue can send to the same queue when
+ * it is named together with the queue manager that we are connected to.
+ */
+func TestProducerForQueueOnLocalQueueManager(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	producer := context.CreateProducerForDestination(queue)
+
+	errSend := producer.SendString(context.CreateQueue("queue://"+cf.QMName+"/DEV.QUEUE.1"), "Sent with the local queue manager")
+	assert.Nil(t, errSend)
+
+	// A queue on another queue manager is a different destination.
+	errSend = producer.SendString(context.CreateQueue("queue://REMOTEQM/DEV.QUEUE.1"), "Not sent")
+	assert.NotNil(t, errSend)
+	if errSend != nil {
+		assert.Equal(t, "UnsupportedOperationException", errSend.GetErrorCode())
+	}
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvBody)
+
+}
+
+/*
+ * Test the URI form of queue names.
+ */
+func TestQueueNameURI(t *testing.T) {
+
+	context := &mqjms.ContextImpl{}
+
+	for name, expected := range map[string][]string{
+		"DEV.QUEUE.1":               {"", "DEV.QUEUE.1"},
+		"queue:///DEV.QUEUE.1":      {"", "DEV.QUEUE.1"},
+		"queue://QM2/DEV.QUEUE.1":   {"QM2", "DEV.QUEUE.1"},
+		"queue://QM2/APP/QUEUE/ONE": {"QM2", "APP/QUEUE/ONE"},
+	} {
+		queue, ok := context.CreateQueue(name).(mqjms.QueueImpl)
+		assert.True(t, ok)
+		assert.Equal(t, expected[0], queue.GetQueueManagerName(), name)
+		assert.Equal(t, expected[1], queue.GetQueueName(), name)
+		assert.Equal(t, expected[1], queue.GetDestinationName(), name)
+	}
+
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/jms20subset"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+/*
+ * Test sending messages using a producer that was created for a destination,
+ * and overriding the options of the producer for a single message.
+ */
+func TestProducerForDestinationWithOptions(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	producer := context.CreateProducerForDestination(queue)
+	assert.Equal(t, queue, producer.GetDestination())
+
+	// The destination of the producer is used when no destination is given.
+	errSend := producer.SendString(nil, "Sent to the destination of the producer")
+	assert.Nil(t, errSend)
+
+	// The options only apply to this message.
+	msg := context.CreateTextMessageWithString("Sent with options")
+	errSend = producer.SendWithOptions(nil, msg, jms20subset.SendOptions{
+		DeliveryMode: jms20subset.DeliveryMode_NON_PERSISTENT,
+		TimeToLive:   60000,
+		Priority:     7,
+	})
+	assert.Nil(t, errSend)
+	assert.Equal(t, jms20subset.DeliveryMode_PERSISTENT, producer.GetDeliveryMode())
+	assert.Equal(t, 0, producer.GetTimeToLive())
+
+	// A producer that was created for a destination cannot send to another one.
+	errSend = producer.SendString(context.CreateQueue("DEV.QUEUE.2"), "Not sent")
+	assert.NotNil(t, errSend)
+	if errSend != nil {
+		assert.Equal(t, "UnsupportedOperationException", errSend.GetErrorCode())
+	}
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvMsg, rcvErr := consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	assert.Equal(t, jms20subset.DeliveryMode_PERSISTENT, rcvMsg.GetJMSDeliveryMode())
+
+	rcvMsg, rcvErr = consumer.ReceiveNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvMsg)
+	assert.Equal(t, jms20subset.DeliveryMode_NON_PERSISTENT, rcvMsg.GetJMSDeliveryMode())
+
+}
+
+/*
+ * Test that invalid send options are rejected.
+ */
+func TestSendOptionsInvalid(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+	producer := context.CreateProducer()
+	msg := context.CreateTextMessageWithString("Not sent")
+
+	for _, options := range []jms20subset.SendOptions{
+		{DeliveryMode: 3},
+		{DeliveryModeSet: true},
+		{TimeToLive: -1},
+		{Priority: 10},
+		{DeliveryDelay: -1},
+	} {
+		errSend := producer.SendWithOptions(queue, msg, options)
+		assert.NotNil(t, errSend)
+		if errSend != nil {
+			assert.Equal(t, "InvalidSendOptions", errSend.GetErrorCode())
+		}
+	}
+
+	// A producer that was created without a destination needs one.
+	errSend := producer.SendString(nil, "Not sent")
+	assert.NotNil(t, errSend)
+	if errSend != nil {
+		assert.Equal(t, "InvalidDestinationException", errSend.GetErrorCode())
+	}
+
+}
+
+/*
+ * Test that a send option can be set explicitly to zero, to override the
+ * option that is set on the producer.
+ */
+func TestSendOptionsExplicitZero(t *testing.T) {
+
+	// Loads CF parameters from connection_info.json and apiKey.json in the Downloads directory
+	cf, cfErr := mqjms.CreateConnectionFactoryFromDefaultJSONFiles()
+	assert.Nil(t, cfErr)
+
+	context, ctxErr := cf.CreateContext()
+	assert.Nil(t, ctxErr)
+	if context != nil {
+		defer context.Close()
+	}
+
+	queue := context.CreateQueue("DEV.QUEUE.1")
+
+	// Messages from this producer expire almost straight away, unless the
+	// time to live is overridden for the message.
+	producer := context.CreateProducer().SetTimeToLive(1)
+
+	msgBody := "Sent without an expiry"
+	msg := context.CreateTextMessageWithString(msgBody)
+	errSend := producer.SendWithOptions(queue, msg, jms20subset.SendOptions{
+		TimeToLive:    0,
+		TimeToLiveSet: true,
+		Priority:      0,
+		PrioritySet:   true,
+	})
+	assert.Nil(t, errSend)
+
+	time.Sleep(100 * time.Millisecond)
+
+	consumer, conErr := context.CreateConsumer(queue)
+	assert.Nil(t, conErr)
+	if consumer != nil {
+		defer consumer.Close()
+	}
+
+	rcvBody, rcvErr := consumer.ReceiveStringBodyNoWait()
+	assert.Nil(t, rcvErr)
+	assert.NotNil(t, rcvBody)
+	if rcvBody != nil {
+		assert.Equal(t, msgBody, *rcvBody)
+	}
+
+}
@@ -214,10 +214,11 @@ func TestInvalidClientAuthValue(t *testing.T) {
 		defer context.Close()
 	}
 
+	// The invalid value is reported without attempting to connect.
 	assert.NotNil(t, errCtx)
 	if errCtx != nil {
-		assert.Equal(t, "MQRC_CD_ERROR", errCtx.GetReason())
-		assert.Equal(t, "2277", errCtx.GetErrorCode())
+		assert.Equal(t, "MQJMS0005", errCtx.GetErrorCode())
+		assert.Contains(t, errCtx.GetReason(), "TLSClientAuth must be NONE or REQUIRED")
 	}
 
 }
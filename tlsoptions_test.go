@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+/*
+ * Test the configuration of the additional TLS options, such as the
+ * distinguished name that the certificate of the queue manager must match.
+ */
+func TestTLSOptions(t *testing.T) {
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:           "QM1",
+		Hostname:         "localhost",
+		PortNumber:       1414,
+		ChannelName:      "DEV.APP.SVRCONN",
+		TLSCipherSpec:    "ANY_TLS12",
+		TLSPeerName:      "CN=QM1,O=IBM,C=GB",
+		TLSKeyResetCount: 40000,
+		TLSFipsRequired:  true,
+	}
+	assert.Empty(t, cf.Validate())
+
+	// The options can be set using a URI.
+	uriCF, err := mqjms.CreateConnectionFactoryFromURI(cf.URI())
+	assert.Nil(t, err)
+	assert.Equal(t, cf, uriCF)
+
+	// Invalid values are reported.
+	badCF := mqjms.ConnectionFactoryImpl{
+		QMName:           "QM1",
+		Hostname:         "localhost",
+		PortNumber:       1414,
+		ChannelName:      "DEV.APP.SVRCONN",
+		TLSPeerName:      "QM1",
+		TLSKeyResetCount: 1000,
+		TLSFipsRequired:  true,
+	}
+
+	assert.Equal(t, []string{
+		"TLSPeerName requires a TLSCipherSpec to be specified",
+		"TLSFipsRequired requires a TLSCipherSpec to be specified",
+		"TLSPeerName must be a distinguished name such as \"CN=QM1,O=IBM\", not \"QM1\"",
+		"TLSKeyResetCount must be zero or between 32768 and 999999999, not 1000",
+	}, errorStrings(badCF.Validate()))
+
+}
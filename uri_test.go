@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"fmt"
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+/*
+ * Test the creation of a ConnectionFactory from a URI string.
+ */
+func TestConnectionFactoryFromURI(t *testing.T) {
+
+	cf, err := mqjms.CreateConnectionFactoryFromURI("mq://app:p%40ssw0rd@host1:1414,host2:1415/QM1" +
+		"?channel=DEV.APP.SVRCONN&cipher=ANY_TLS12&clientAuth=REQUIRED&keyRepository=/var/mqm/ssl/key" +
+		"&certLabel=myCert&reconnect=qmgr&reconnectTimeout=60")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "QM1", cf.QMName)
+	assert.Equal(t, "host1(1414),host2(1415)", cf.ConnectionNameList)
+	assert.Equal(t, "DEV.APP.SVRCONN", cf.ChannelName)
+	assert.Equal(t, "app", cf.UserName)
+	assert.Equal(t, "p@ssw0rd", cf.Password)
+	assert.Equal(t, mqjms.TransportType_CLIENT, cf.TransportType)
+	assert.Equal(t, "ANY_TLS12", cf.TLSCipherSpec)
+	assert.Equal(t, mqjms.TLSClientAuth_REQUIRED, cf.TLSClientAuth)
+	assert.Equal(t, "/var/mqm/ssl/key", cf.KeyRepository)
+	assert.Equal(t, "myCert", cf.CertificateLabel)
+	assert.Equal(t, mqjms.ClientReconnect_QMGR, cf.ClientReconnectOptions)
+	assert.Equal(t, 60, cf.ClientReconnectTimeout)
+
+	// A single host without a port uses the default MQ port.
+	cf2, err2 := mqjms.CreateConnectionFactoryFromURI("mq://localhost/QM1?channel=DEV.APP.SVRCONN")
+	assert.Nil(t, err2)
+	assert.Equal(t, "localhost", cf2.Hostname)
+	assert.Equal(t, 1414, cf2.PortNumber)
+	assert.Equal(t, "", cf2.ConnectionNameList)
+
+	// A bindings connection does not specify a host.
+	cf3, err3 := mqjms.CreateConnectionFactoryFromURI("mq:///QM1?transport=bindings")
+	assert.Nil(t, err3)
+	assert.Equal(t, mqjms.TransportType_BINDINGS, cf3.TransportType)
+	assert.Equal(t, "QM1", cf3.QMName)
+
+}
+
+/*
+ * Test that malformed connection URIs are rejected with a useful error.
+ */
+func TestConnectionFactoryFromURIErrors(t *testing.T) {
+
+	badURIs := map[string]string{
+		"amqp://localhost/QM1":                      "Connection URI must start with mq://",
+		"mq://localhost:99999/QM1":                  "Invalid port \"99999\" for host \"localhost\" in connection URI",
+		"mq://localhost:abc/QM1":                    "Invalid port \"abc\" for host \"localhost\" in connection URI",
+		"mq://host1,,host2/QM1":                     "Connection URI contains an empty host",
+		"mq://:secret@localhost/QM1":                "Connection URI contains a password but no user name",
+		"mq://localhost/QM1/extra":                  "Connection URI path must only contain the queue manager name: /QM1/extra",
+		"mq://localhost/QM1?transport=tcp":          "Invalid value \"tcp\" for connection URI parameter transport, expected client or bindings",
+		"mq://localhost/QM1?clientAuth=MAYBE":       "Invalid value \"MAYBE\" for connection URI parameter clientAuth, expected NONE or REQUIRED",
+		"mq://localhost/QM1?reconnect=always":       "Invalid value \"always\" for connection URI parameter reconnect, expected one of asdef, disabled, reconnect or qmgr",
+		"mq://localhost/QM1?reconnectTimeout=-1":    "Invalid value \"-1\" for connection URI parameter reconnectTimeout, expected a non-negative number of seconds",
+		"mq://localhost/QM1?chanel=DEV.APP.SVRCONN": "Unknown connection URI parameter: chanel",
+		"mq://localhost/QM1?channel=A&channel=B":    "Connection URI parameter channel must only be specified once",
+		"mq://localhost/QM1?transport=bindings":     "Connection URI must not contain a host when transport=bindings",
+		"mq:///QM1?channel=DEV.APP.SVRCONN":         "Connection URI must contain a host, or the ccdt parameter, when transport=client",
+	}
+
+	for uri, expectedErr := range badURIs {
+		_, err := mqjms.CreateConnectionFactoryFromURI(uri)
+		if assert.NotNil(t, err, uri) {
+			assert.Equal(t, expectedErr, err.Error(), uri)
+		}
+	}
+
+}
+
+/*
+ * Test that the string form of a ConnectionFactory masks the password, and
+ * that the URI can be used to recreate the ConnectionFactory.
+ */
+func TestConnectionFactoryURIRoundTrip(t *testing.T) {
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:                 "QM1",
+		ConnectionNameList:     "host1(1414),host2(1415)",
+		ChannelName:            "DEV.APP.SVRCONN",
+		UserName:               "app",
+		Password:               "p@ss/w:rd",
+		TLSCipherSpec:          "ANY_TLS12",
+		TLSClientAuth:          mqjms.TLSClientAuth_NONE,
+		KeyRepository:          "/var/mqm/ssl/key",
+		CertificateLabel:       "myCert",
+		ClientReconnectOptions: mqjms.ClientReconnect_RECONNECT,
+		ClientReconnectTimeout: 120,
+	}
+
+	// The password is not included when the ConnectionFactory is printed.
+	assert.NotContains(t, cf.String(), "p@ss")
+	assert.NotContains(t, fmt.Sprintf("%+v", cf), "p@ss")
+	assert.Equal(t, "mq://app:********@host1:1414,host2:1415/QM1?certLabel=myCert&channel=DEV.APP.SVRCONN"+
+		"&cipher=ANY_TLS12&clientAuth=NONE&keyRepository=%2Fvar%2Fmqm%2Fssl%2Fkey&reconnect=reconnect"+
+		"&reconnectTimeout=120", cf.String())
+
+	// The masked form can be parsed, which recreates everything apart from the password.
+	masked, err := mqjms.CreateConnectionFactoryFromURI(cf.String())
+	assert.Nil(t, err)
+	expectedMasked := cf
+	expectedMasked.Password = "********"
+	assert.Equal(t, expectedMasked, masked)
+
+	// The full URI recreates an identical ConnectionFactory.
+	roundTrip, err2 := mqjms.CreateConnectionFactoryFromURI(cf.URI())
+	assert.Nil(t, err2)
+	assert.Equal(t, cf, roundTrip)
+
+	// Credentials containing the characters that separate the parts of the
+	// URI are escaped, so that they are read back unchanged.
+	credsCF := cf
+	credsCF.UserName = "corp:app@dept"
+	credsCF.Password = "p@ss:w/rd?#"
+	assert.True(t, strings.HasPrefix(credsCF.URI(), "mq://corp%3Aapp%40dept:p%40ss%3Aw%2Frd%3F%23@host1:1414"), credsCF.URI())
+	credsRoundTrip, credsErr := mqjms.CreateConnectionFactoryFromURI(credsCF.URI())
+	assert.Nil(t, credsErr)
+	assert.Equal(t, credsCF, credsRoundTrip)
+
+	// The same is true for a bindings connection.
+	bindingsCF := mqjms.ConnectionFactoryImpl{
+		QMName:        "QM1",
+		TransportType: mqjms.TransportType_BINDINGS,
+	}
+	assert.Equal(t, "mq:///QM1?transport=bindings", bindingsCF.String())
+	bindingsRoundTrip, err3 := mqjms.CreateConnectionFactoryFromURI(bindingsCF.URI())
+	assert.Nil(t, err3)
+	assert.Equal(t, bindingsCF, bindingsRoundTrip)
+
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) IBM Corporation 2019
+ *
+ * This program and the accompanying materials are made available under the
+ * terms of the Eclipse Public License v. 2.0, which is available at
+ * http://www.eclipse.org/legal/epl-2.0.
+ *
+ * SPDX-License-Identifier: EPL-2.0
+ */
+package main
+
+import (
+	"github.com/ibm-messaging/mq-golang-jms20/mqjms"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+ * Test that every problem with the configuration of a ConnectionFactory is
+ * reported at once.
+ */
+func TestConnectionFactoryValidate(t *testing.T) {
+
+	// A valid configuration has no problems.
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:      "QM1",
+		Hostname:    "localhost",
+		PortNumber:  1414,
+		ChannelName: "DEV.APP.SVRCONN",
+	}
+	assert.Empty(t, cf.Validate())
+
+	// An invalid configuration reports each problem.
+	badCF := mqjms.ConnectionFactoryImpl{
+		QMName:           "QM1",
+		Hostname:         "localhost",
+		PortNumber:       70000,
+		Password:         "passw0rd",
+		TLSCipherSpec:    "ANY_TLS12",
+		TLSClientAuth:    "OPTIONAL",
+		CertificateLabel: "myCert",
+	}
+
+	assert.Equal(t, []string{
+		"UserName must be specified when a Password is specified",
+		"ChannelName must be specified for a client connection",
+		"PortNumber must be between 1 and 65535, not 70000",
+		"TLSClientAuth must be NONE or REQUIRED, not \"OPTIONAL\"",
+		"CertificateLabel requires a KeyRepository to be specified",
+	}, errorStrings(badCF.Validate()))
+
+	// CreateContext reports the problems without attempting to connect.
+	context, ctxErr := badCF.CreateContext()
+	assert.Nil(t, context)
+	assert.NotNil(t, ctxErr)
+	assert.Equal(t, "MQJMS0005", ctxErr.GetErrorCode())
+
+	// Options that conflict with each other are reported.
+	conflictCF := mqjms.ConnectionFactoryImpl{
+		QMName:             "QM1",
+		Hostname:           "localhost",
+		PortNumber:         1414,
+		ConnectionNameList: "host1(1414),host2(abc)",
+		CCDTURL:            "file:///var/mqm/ccdt.json",
+	}
+
+	assert.Equal(t, []string{
+		"Hostname must not be specified when a CCDTURL is specified",
+		"ConnectionNameList must not be specified when a CCDTURL is specified",
+		"Hostname and ConnectionNameList must not both be specified",
+		"ConnectionNameList contains an invalid connection name: Invalid port \"abc\" in \"host2(abc)\"",
+	}, errorStrings(conflictCF.Validate()))
+
+	// The network settings are ignored for a bindings connection, so that a
+	// client configuration can be switched to bindings.
+	bindingsCF := mqjms.ConnectionFactoryImpl{
+		QMName:        "QM1",
+		TransportType: mqjms.TransportType_BINDINGS,
+		Hostname:      "localhost",
+		PortNumber:    1414,
+		ChannelName:   "DEV.APP.SVRCONN",
+	}
+	assert.Empty(t, bindingsCF.Validate())
+
+}
+
+/*
+ * Test that the key repository and its stash file must exist.
+ */
+func TestConnectionFactoryValidateKeyRepository(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "mqjms")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	keyRepository := filepath.Join(dir, "key")
+	ioutil.WriteFile(keyRepository+".kdb", []byte{}, 0600)
+
+	cf := mqjms.ConnectionFactoryImpl{
+		QMName:        "QM1",
+		Hostname:      "localhost",
+		PortNumber:    1414,
+		ChannelName:   "DEV.APP.SVRCONN",
+		TLSCipherSpec: "ANY_TLS12",
+		KeyRepository: keyRepository,
+	}
+
+	// The stash file is missing.
+	errs := cf.Validate()
+	assert.Equal(t, 1, len(errs))
+	assert.Contains(t, errs[0].Error(), "KeyRepository file "+keyRepository+".sth cannot be read")
+
+	ioutil.WriteFile(keyRepository+".sth", []byte{}, 0600)
+	assert.Empty(t, cf.Validate())
+
+}
+
+// errorStrings converts a list of errors into their messages, so that they
+// can be easily compared.
+func errorStrings(errs []error) []string {
+
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+
+	return msgs
+}